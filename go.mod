@@ -6,6 +6,11 @@ require (
 	gioui.org v0.7.1
 	github.com/fsnotify/fsnotify v1.7.0
 	github.com/skratchdot/open-golang v0.0.0-20200116055534-eef842397966
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+	golang.org/x/image v0.18.0
+	golang.org/x/sys v0.22.0
+	golang.org/x/text v0.16.0
 )
 
 require (
@@ -15,7 +20,5 @@ require (
 	github.com/go-text/typesetting-utils v0.0.0-20240329101916-eee87fb235a3 // indirect
 	golang.org/x/exp v0.0.0-20240707233637-46b078467d37 // indirect
 	golang.org/x/exp/shiny v0.0.0-20240707233637-46b078467d37 // indirect
-	golang.org/x/image v0.18.0 // indirect
-	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/text v0.16.0 // indirect
+	golang.org/x/net v0.27.0 // indirect
 )
@@ -1,16 +1,47 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"image"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"gioui.org/app"
+	"gioui.org/io/key"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
-	"github.com/ShakedGold/Gole/pkg/fluent"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/ShakedGold/Gole/pkg/assets"
+	"github.com/ShakedGold/Gole/pkg/explorer"
+	"github.com/ShakedGold/Gole/pkg/explorer/fileops"
+	"github.com/ShakedGold/Gole/pkg/explorer/index"
+	"github.com/ShakedGold/Gole/pkg/explorer/journal"
+	"github.com/ShakedGold/Gole/pkg/explorer/search"
+	"github.com/ShakedGold/Gole/pkg/explorer/trash"
+	"github.com/ShakedGold/Gole/pkg/widgets/entry"
+	"github.com/ShakedGold/Gole/pkg/widgets/menubar"
+	"github.com/ShakedGold/Gole/pkg/widgets/menubar/clickable"
+	"github.com/ShakedGold/Gole/pkg/widgets/menubar/dropdown"
+	"github.com/ShakedGold/Gole/pkg/widgets/menubar/editor"
+	"github.com/ShakedGold/Gole/pkg/widgets/preview"
+	"github.com/ShakedGold/Gole/pkg/widgets/results"
+)
+
+// toolbarIconSize and viewIconSize are how large the icons of the toolbar
+// and of the view mode button are.
+const (
+	toolbarIconSize = unit.Dp(25)
+	viewIconSize    = unit.Dp(30)
 )
 
 func main() {
@@ -27,117 +58,894 @@ func main() {
 }
 
 func run(window *app.Window) error {
-	theme := fluent.NewTheme()
-
-	// entries, err := explorer.Home()
-	// if err != nil {
-	// 	return err
-	// }
-	// entries, err = entries.Prepare()
-	// if err != nil {
-	// 	return err
-	// }
-
-	// // watch the home directory
-	// watcher, err := explorer.Watcher(entries.Path)
-	// if err != nil {
-	// 	return err
-	// }
-
-	// // watch for events, if there is a change in the filesystem
-	// go func() {
-	// 	explorer.Watch(watcher, entries)
-	// }()
-
-	// // get up asset
-	// up, err := assets.GetImage("up.png")
-	// if err != nil {
-	// 	return err
-	// }
-
-	// list, err := assets.GetImage("list.png")
-	// if err != nil {
-	// 	return err
-	// }
-
-	// grid, err := assets.GetImage("grid.png")
-	// if err != nil {
-	// 	return err
-	// }
-
-	// pathEditor := new(widget.Editor)
-	// pathEditor.SetText(entries.Path)
-
-	// // create the menu
-	// menu := menubar.NewMenubar()
-	// upMenuItem := clickable.ClickableMenuItem{
-	// 	Clickable: new(widget.Clickable),
-	// 	OnClick: func(gtx layout.Context) {
-	// 		previousPath := filepath.Join(entries.Path, "..")
-	// 		previousEntries, err := entry.ReadPath(previousPath)
-	// 		if err != nil {
-	// 			log.Println(err)
-	// 			return
-	// 		}
-	// 		entries.Update(previousEntries)
-	// 		pathEditor.SetText(previousEntries.Path)
-	// 	},
-	// 	LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
-	// 		return widget.Image{
-	// 			Src:   paint.NewImageOp(*up),
-	// 			Scale: 0.5,
-	// 		}.Layout(gtx)
-	// 	},
-	// }
-
-	// pathMenuItem := editor.EditorInputItem{
-	// 	Editor: pathEditor,
-	// 	Flexed: true,
-	// }
-
-	// viewMenuItem := clickable.ClickableMenuItem{
-	// 	Clickable: new(widget.Clickable),
-	// 	OnClick: func(gtx layout.Context) {
-	// 		entries.ViewMode = 1 - entries.ViewMode
-	// 	},
-	// 	LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
-	// 		var image widget.Image
-	// 		var label material.LabelStyle
-
-	// 		if entries.ViewMode == entry.ViewModeGrid {
-	// 			image = widget.Image{
-	// 				Src:   paint.NewImageOp(*grid),
-	// 				Scale: 0.6,
-	// 			}
-	// 			label = material.H6(th, "Grid")
-	// 		} else {
-	// 			image = widget.Image{
-	// 				Src:   paint.NewImageOp(*list),
-	// 				Scale: 0.5,
-	// 			}
-	// 			label = material.H6(th, "List")
-	// 		}
-	// 		return layout.Flex{
-	// 			Axis:      layout.Horizontal,
-	// 			Alignment: layout.Middle,
-	// 		}.Layout(gtx,
-	// 			layout.Rigid(image.Layout),
-	// 			layout.Rigid(label.Layout),
-	// 		)
-	// 	},
-	// }
-
-	// menu.AddMenuItem(upMenuItem)
-	// menu.AddMenuItem(pathMenuItem)
-	// menu.AddMenuItem(viewMenuItem)
+	theme := material.NewTheme()
 
-	var ops op.Ops
+	entries, err := explorer.Home()
+	if err != nil {
+		return err
+	}
+	entries, err = entries.Prepare()
+	if err != nil {
+		return err
+	}
+
+	// entries added from other goroutines need a new frame to show up
+	entries.Invalidate = window.Invalidate
+	// the home folder is read in the background like any other
+	entries.Load()
+
+	// the columns of the details view are remembered between runs
+	entries.Details, err = entry.DefaultDetails()
+	if err != nil {
+		log.Println(err)
+		entries.Details = entry.NewDetails()
+	}
+
+	// watch the home directory
+	watcher, err := explorer.Watcher(entries.Path)
+	if err != nil {
+		return err
+	}
+
+	// watch for events, if there is a change in the filesystem
+	go func() {
+		explorer.Watch(watcher, entries)
+	}()
+
+	history := explorer.NewHistory(entries.Path)
+
+	operations := fileops.NewEngine(fileops.KeepBoth)
+
+	// every change made to the disk is journaled so it can be undone
+	changes, err := journal.Default()
+	if err != nil {
+		return err
+	}
+
+	// the file index answers name searches under its roots without walking
+	// the disk, it is off unless turned on
+	indexConfig, err := index.DefaultConfig()
+	if err != nil {
+		log.Println(err)
+		indexConfig = index.NewConfig()
+	}
+	var files *index.Index
+	startIndex := func() {
+		files = index.New(*indexConfig)
+		path, err := index.DefaultPath()
+		if err != nil {
+			log.Println(err)
+		}
+		files.Path = path
+		files.OnChange = window.Invalidate
+		if err := files.Start(); err != nil {
+			log.Println(err)
+		}
+	}
+	if indexConfig.Enabled {
+		startIndex()
+	}
+	defer func() {
+		if files != nil {
+			if err := files.Stop(); err != nil {
+				log.Println(err)
+			}
+		}
+	}()
 
-	button, err := fluent.Button(theme, "Semibold weight", nil)
+	// get up asset
+	up, err := assets.Icon("up.png")
 	if err != nil {
 		return err
 	}
-	button.Appearance = fluent.Primary
+
+	backward, err := assets.Icon("backward.png")
+	if err != nil {
+		return err
+	}
+
+	forward, err := assets.Icon("forward.png")
+	if err != nil {
+		return err
+	}
+
+	list, err := assets.Icon("list.png")
+	if err != nil {
+		return err
+	}
+
+	grid, err := assets.Icon("grid.png")
+	if err != nil {
+		return err
+	}
+
+	pathEditor := &widget.Editor{
+		SingleLine: true,
+		Submit:     true,
+	}
+	pathEditor.SetText(entries.Path)
+
+	// the filter applies to the folder being shown, it starts over in the
+	// next one
+	filterEditor := &widget.Editor{
+		SingleLine: true,
+	}
+	clearFilter := func() {
+		filterEditor.SetText("")
+		entries.Filter.Pattern = ""
+	}
+
+	// status is shown at the bottom of the window
+	status := ""
+
+	// the search running, if any, stops when the explorer moves on
+	cancelSearch := func() {}
+	type searchResult struct {
+		ctx   context.Context
+		found int
+		err   error
+	}
+	searchDone := make(chan searchResult, 1)
+	// finish hands the outcome of a search to the next frame, replacing
+	// any outcome not seen yet
+	finish := func(done chan searchResult, result searchResult) {
+		for {
+			select {
+			case done <- result:
+				window.Invalidate()
+				return
+			default:
+			}
+			select {
+			case <-done:
+			default:
+			}
+		}
+	}
+
+	// the matches of the last search in the content of files
+	cancelGrep := func() {}
+	grepDone := make(chan searchResult, 1)
+	findings := results.New()
+	findings.Invalidate = window.Invalidate
+	findings.OnOpen = func(m search.Match) {
+		if err := explorer.OpenAt(m.Path, m.Line); err != nil {
+			log.Println(err)
+		}
+	}
+	showFindings := false
+
+	// the preview of the selected file, right of the entries
+	filePreview := preview.New()
+	filePreview.Invalidate = window.Invalidate
+	showPreview := false
+
+	// show moves the explorer to path without touching the history
+	show := func(path string) error {
+		// the folder shown is read again in place in the background, the
+		// changes show up keeping the selection like the watcher's do
+		if path == entries.Path && !entries.Virtual {
+			go entries.Reload()
+			return nil
+		}
+
+		var newEntries *entry.Entries
+		var err error
+		if trash.IsTrash(path) {
+			newEntries, err = trash.View()
+		} else {
+			newEntries, err = entry.Open(path)
+		}
+		if err != nil {
+			return err
+		}
+
+		watcher.Remove(entries.Path)
+		watcher.Add(newEntries.Path)
+
+		cancelSearch()
+		clearFilter()
+		entries.Update(newEntries)
+		pathEditor.SetText(newEntries.Path)
+		return nil
+	}
+
+	// startSearch shows the results of searching under the current folder
+	// while they come in
+	startSearch := func(text string) {
+		query, err := search.Parse(text, time.Now())
+		if err != nil {
+			status = err.Error()
+			return
+		}
+
+		cancelSearch()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelSearch = cancel
+
+		root := entries.Path
+		clearFilter()
+		entries.Update(search.View(root))
+		add := entries.Adder()
+		status = fmt.Sprintf("Searching %s...", root)
+
+		// the index is only used once complete, and it knows nothing of
+		// .gitignore files
+		walk := search.Walk
+		if files != nil && files.Covers(root) && !query.Ignored && (!query.Hidden || files.Config.Hidden) {
+			if s := files.Status(); !s.Crawling && s.Err == nil {
+				walk = files.Walk
+			}
+		}
+
+		go func() {
+			found := 0
+			err := search.RunWith(ctx, walk, root, query, func(entrys ...entry.Entry) bool {
+				found += len(entrys)
+				return add(entrys...)
+			})
+			finish(searchDone, searchResult{ctx: ctx, found: found, err: err})
+		}()
+	}
+
+	// startGrep shows the lines of the files under the current folder that
+	// have text in them
+	startGrep := func(text string) {
+		pattern, err := search.GrepPattern(text)
+		if err != nil {
+			status = err.Error()
+			return
+		}
+
+		cancelGrep()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelGrep = cancel
+
+		root := entries.Path
+		findings.Reset(root)
+		showFindings = true
+		add := findings.Adder()
+		status = fmt.Sprintf("Searching the files in %s...", root)
+
+		go func() {
+			found := 0
+			err := search.Grep(ctx, root, search.Query{MaxSize: -1}, pattern, search.DefaultMaxFileSize, func(m search.Match) bool {
+				found++
+				return add(m)
+			})
+			finish(grepDone, searchResult{ctx: ctx, found: found, err: err})
+		}()
+	}
+
+	// navigate moves the explorer to path and records it in the history
+	navigate := func(path string) {
+		if err := show(path); err != nil {
+			log.Println(err)
+			return
+		}
+		history.Visit(entries.Path)
+	}
+
+	// create the menu
+	menu := menubar.NewMenubar()
+	backMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			path, ok := history.Back()
+			if !ok {
+				return
+			}
+			if err := show(path); err != nil {
+				log.Println(err)
+			}
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return assets.LayoutIcon(gtx, backward, toolbarIconSize)
+		},
+	}
+
+	forwardMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			path, ok := history.Forward()
+			if !ok {
+				return
+			}
+			if err := show(path); err != nil {
+				log.Println(err)
+			}
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return assets.LayoutIcon(gtx, forward, toolbarIconSize)
+		},
+	}
+
+	upMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			navigate(filepath.Join(entries.Path, ".."))
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return assets.LayoutIcon(gtx, up, toolbarIconSize)
+		},
+	}
+
+	historyMenuItem := dropdown.NewDropdownMenuItem(
+		history.Items,
+		func(gtx layout.Context, index int) {
+			path, ok := history.Jump(index)
+			if !ok {
+				return
+			}
+			if err := show(path); err != nil {
+				log.Println(err)
+			}
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "History").Layout(gtx)
+		},
+	)
+	historyMenuItem.Selected = history.Index
+
+	// undo and redo refresh the current folder since it may have changed
+	// a refused or partial undo says why, part of it may have been done
+	undo := func() {
+		if _, err := changes.Undo(); err != nil {
+			status = err.Error()
+		}
+		if err := show(entries.Path); err != nil {
+			status = err.Error()
+		}
+	}
+
+	redo := func() {
+		if _, err := changes.Redo(); err != nil {
+			status = err.Error()
+		}
+		if err := show(entries.Path); err != nil {
+			status = err.Error()
+		}
+	}
+
+	undoMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			undo()
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			label := material.H6(th, "Undo")
+			if !changes.CanUndo() {
+				label.Color = th.Palette.ContrastBg
+				label.Color.A = 0x60
+			}
+			return label.Layout(gtx)
+		},
+	}
+
+	redoMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			redo()
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			label := material.H6(th, "Redo")
+			if !changes.CanRedo() {
+				label.Color = th.Palette.ContrastBg
+				label.Color.A = 0x60
+			}
+			return label.Layout(gtx)
+		},
+	}
+
+	// updatePreview shows the selected file in the preview, if only one is.
+	// It is called every frame, as the file may change without the
+	// selection changing.
+	updatePreview := func() {
+		if !showPreview {
+			filePreview.Clear()
+			return
+		}
+		selected := entries.Selected()
+		if len(selected) == 1 {
+			filePreview.Show(selected[0])
+		} else {
+			filePreview.Clear()
+		}
+	}
+
+	// the status line tells how many entries are selected
+	entries.Selection.OnChange = func(paths []string) {
+		switch len(paths) {
+		case 0:
+			status = ""
+		case 1:
+			status = "1 item selected"
+		default:
+			status = fmt.Sprintf("%d items selected", len(paths))
+		}
+	}
+
+	// emptying is how many items were in the trash when emptying it was
+	// asked for, nothing is deleted until that is confirmed
+	emptying := 0
+	confirmEmptyButton := new(widget.Clickable)
+	cancelEmptyButton := new(widget.Clickable)
+
+	trashMenuItem := dropdown.NewDropdownMenuItem(
+		func() []string {
+			return []string{"Open Trash", "Empty Trash"}
+		},
+		func(gtx layout.Context, index int) {
+			home, err := trash.Home()
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			trashFiles := filepath.Join(home.Dir, "files")
+
+			switch index {
+			case 0:
+				navigate(trashFiles)
+			case 1:
+				items, err := trash.Items()
+				if err != nil {
+					status = err.Error()
+					return
+				}
+				if len(items) == 0 {
+					status = "The trash is empty"
+					return
+				}
+				emptying = len(items)
+			}
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "Trash").Layout(gtx)
+		},
+	)
+
+	pathMenuItem := editor.EditorInputItem{
+		Editor: pathEditor,
+		Flexed: true,
+		Hint:   "Path",
+	}
+
+	searchEditor := &widget.Editor{
+		SingleLine: true,
+		Submit:     true,
+	}
+	searchMenuItem := editor.EditorInputItem{
+		Editor: searchEditor,
+		Hint:   "Search",
+		Width:  unit.Dp(200),
+	}
+
+	grepEditor := &widget.Editor{
+		SingleLine: true,
+		Submit:     true,
+	}
+	grepMenuItem := editor.EditorInputItem{
+		Editor: grepEditor,
+		Hint:   "Find in files",
+		Width:  unit.Dp(200),
+	}
+
+	filterMenuItem := editor.EditorInputItem{
+		Editor: filterEditor,
+		Hint:   "Filter",
+		Width:  unit.Dp(160),
+	}
+
+	newFolderMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			folder, err := operations.CreateFolder(entries.Path, "New Folder")
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			if err := changes.Record(journal.KindCreateFolder, nil, []string{folder}); err != nil {
+				log.Println(err)
+			}
+			if err := show(entries.Path); err != nil {
+				log.Println(err)
+			}
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "New Folder").Layout(gtx)
+		},
+	}
+
+	// clipboard holds the paths copied or cut from the selection
+	var clipboard []string
+	var cut bool
+
+	// the paste running, if any, copies or moves in the background while
+	// its progress is shown under the entries
+	type pasteResult struct {
+		kind      journal.Kind
+		transfers []fileops.Transfer
+		err       error
+	}
+	var cancelPaste context.CancelFunc
+	pasteDone := make(chan pasteResult, 1)
+	var pasteLock sync.Mutex
+	var pasteProgress fileops.Progress
+	cancelPasteButton := new(widget.Clickable)
+
+	// renaming is the path a new name is asked for in the status line
+	renaming := ""
+	renameEditor := &widget.Editor{
+		SingleLine: true,
+		Submit:     true,
+	}
+	startRename := func(gtx layout.Context) {
+		selected := entries.SelectedPaths()
+		if len(selected) != 1 {
+			status = "Select a single item to rename"
+			return
+		}
+
+		renaming = selected[0]
+		name := filepath.Base(renaming)
+		renameEditor.SetText(name)
+		// the extension stays unless it is typed over
+		renameEditor.SetCaret(len([]rune(strings.TrimSuffix(name, filepath.Ext(name)))), 0)
+		gtx.Execute(key.FocusCmd{Tag: renameEditor})
+	}
+
+	editActions := []string{"Copy", "Cut", "Paste", "Rename", "Move to Trash", "Restore from Trash"}
+	editMenuItem := dropdown.NewDropdownMenuItem(
+		func() []string {
+			return editActions
+		},
+		func(gtx layout.Context, index int) {
+			selected := entries.SelectedPaths()
+
+			switch editActions[index] {
+			case "Copy", "Cut":
+				clipboard = selected
+				cut = editActions[index] == "Cut"
+				return
+			case "Rename":
+				startRename(gtx)
+				return
+			case "Paste":
+				if len(clipboard) == 0 {
+					return
+				}
+				if cancelPaste != nil {
+					status = "Wait for the paste running to finish"
+					return
+				}
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancelPaste = cancel
+				pasteProgress = fileops.Progress{}
+
+				// the paste has an engine of its own so the operations made
+				// meanwhile don't mix with its progress
+				engine := fileops.NewEngine(operations.Conflict)
+				engine.OnProgress = func(p fileops.Progress) {
+					pasteLock.Lock()
+					pasteProgress = p
+					pasteLock.Unlock()
+					window.Invalidate()
+				}
+
+				sources, destination, move := clipboard, entries.Path, cut
+				if cut {
+					// cut files can only be pasted once
+					clipboard = nil
+				}
+				go func() {
+					result := pasteResult{kind: journal.KindCopy}
+					if move {
+						result.kind = journal.KindMove
+						result.transfers, result.err = engine.Move(ctx, sources, destination)
+					} else {
+						result.transfers, result.err = engine.Copy(ctx, sources, destination)
+					}
+					pasteDone <- result
+					window.Invalidate()
+				}()
+				return
+			case "Move to Trash":
+				var from, to []string
+				for _, path := range selected {
+					item, err := trash.Put(path)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					from = append(from, path)
+					to = append(to, item.File())
+				}
+				if err := changes.Record(journal.KindTrash, from, to); err != nil {
+					log.Println(err)
+				}
+			case "Restore from Trash":
+				var from, to []string
+				for _, path := range selected {
+					item, ok := trash.Lookup(path)
+					if !ok {
+						continue
+					}
+					restored, err := trash.Restore(item)
+					if err != nil {
+						status = err.Error()
+						continue
+					}
+					from = append(from, path)
+					to = append(to, restored)
+				}
+				if err := changes.Record(journal.KindRestore, from, to); err != nil {
+					log.Println(err)
+				}
+			}
+
+			// the folder shown is gone once it is trashed or restored
+			path := entries.Path
+			for _, err := os.Stat(path); err != nil && filepath.Dir(path) != path; _, err = os.Stat(path) {
+				path = filepath.Dir(path)
+			}
+			if err := show(path); err != nil {
+				log.Println(err)
+			}
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "Edit").Layout(gtx)
+		},
+	)
+
+	viewMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			// cycle through the list, grid and details views
+			entries.ViewMode = (entries.ViewMode + 1) % (entry.ViewModeDetails + 1)
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			icon := list
+			var label material.LabelStyle
+
+			switch entries.ViewMode {
+			case entry.ViewModeGrid:
+				icon = grid
+				label = material.H6(th, "Grid")
+			case entry.ViewModeDetails:
+				label = material.H6(th, "Details")
+			default:
+				label = material.H6(th, "List")
+			}
+			return layout.Flex{
+				Axis:      layout.Horizontal,
+				Alignment: layout.Middle,
+			}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return assets.LayoutIcon(gtx, icon, viewIconSize)
+				}),
+				layout.Rigid(label.Layout),
+			)
+		},
+	}
+
+	previewMenuItem := clickable.ClickableMenuItem{
+		Clickable: new(widget.Clickable),
+		OnClick: func(gtx layout.Context) {
+			showPreview = !showPreview
+		},
+		LayoutCallback: func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			label := material.H6(th, "Preview")
+			if showPreview {
+				label.Color = th.Palette.ContrastBg
+			}
+			return label.Layout(gtx)
+		},
+	}
+
+	// check marks the items of a dropdown that are on
+	check := func(checked bool, title string) string {
+		if checked {
+			return "✓ " + title
+		}
+		return "   " + title
+	}
+
+	filterOptions := []struct {
+		title string
+		value *bool
+	}{
+		{"Show hidden files", &entries.Filter.ShowHidden},
+		{"Regular expression", &entries.Filter.Regex},
+	}
+	filterOptionsMenuItem := dropdown.NewDropdownMenuItem(
+		func() []string {
+			var items []string
+			for _, option := range filterOptions {
+				items = append(items, check(*option.value, option.title))
+			}
+			for _, fileType := range entry.FileTypes {
+				items = append(items, check(entries.Filter.Type == fileType, fileType.Title()))
+			}
+			return items
+		},
+		func(gtx layout.Context, index int) {
+			if index < len(filterOptions) {
+				option := filterOptions[index]
+				*option.value = !*option.value
+			} else {
+				entries.Filter.Type = entry.FileTypes[index-len(filterOptions)]
+			}
+			entries.Prepare()
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "Show").Layout(gtx)
+		},
+	)
+
+	columnsMenuItem := dropdown.NewDropdownMenuItem(
+		func() []string {
+			var items []string
+			for _, column := range entry.Columns {
+				items = append(items, check(!entries.Details.IsHidden(column), column.Title()))
+			}
+			return items
+		},
+		func(gtx layout.Context, index int) {
+			entries.Details.Toggle(entry.Columns[index])
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "Columns").Layout(gtx)
+		},
+	)
+
+	// the sort orders offered, followed by the sorting options
+	sortOrders := []entry.SortBy{
+		entry.SortByName,
+		entry.SortBySize,
+		entry.SortByModified,
+		entry.SortByExtension,
+		entry.SortByType,
+	}
+	sortOptions := []struct {
+		title string
+		value *bool
+	}{
+		{"Descending", &entries.Sorter.Descending},
+		{"Folders first", &entries.Sorter.FoldersFirst},
+		{"Natural order", &entries.Sorter.Natural},
+		{"Case sensitive", &entries.Sorter.CaseSensitive},
+	}
+
+	sortMenuItem := dropdown.NewDropdownMenuItem(
+		func() []string {
+			var items []string
+			for _, by := range sortOrders {
+				items = append(items, check(entries.Sorter.By == by, by.Title()))
+			}
+			for _, option := range sortOptions {
+				items = append(items, check(*option.value, option.title))
+			}
+			return items
+		},
+		func(gtx layout.Context, index int) {
+			if index < len(sortOrders) {
+				entries.Sorter.By = sortOrders[index]
+			} else {
+				option := sortOptions[index-len(sortOrders)]
+				*option.value = !*option.value
+			}
+			entries.Prepare()
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "Sort").Layout(gtx)
+		},
+	)
+
+	groupings := []entry.GroupBy{
+		entry.GroupNone,
+		entry.GroupByType,
+		entry.GroupByDate,
+		entry.GroupBySize,
+	}
+	groupMenuItem := dropdown.NewDropdownMenuItem(
+		func() []string {
+			var items []string
+			for _, by := range groupings {
+				items = append(items, check(entries.Sorter.Group == by, by.Title()))
+			}
+			return items
+		},
+		func(gtx layout.Context, index int) {
+			entries.Sorter.Group = groupings[index]
+			entries.Prepare()
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "Group").Layout(gtx)
+		},
+	)
+
+	indexMenuItem := dropdown.NewDropdownMenuItem(
+		func() []string {
+			items := []string{check(files != nil, "Index files")}
+			if files == nil {
+				return items
+			}
+
+			s := files.Status()
+			if s.Paused {
+				items = append(items, "   Resume")
+			} else {
+				items = append(items, "   Pause")
+			}
+			items = append(items, "   Rebuild")
+
+			switch {
+			case s.Err != nil:
+				items = append(items, fmt.Sprintf("   %d files, %s", s.Files, s.Err))
+			case s.Crawling:
+				items = append(items, fmt.Sprintf("   Indexing, %d files so far", s.Files))
+			default:
+				items = append(items, fmt.Sprintf("   %d files indexed", s.Files))
+			}
+			return items
+		},
+		func(gtx layout.Context, index int) {
+			switch {
+			case index == 0 && files == nil:
+				indexConfig.Enabled = true
+				startIndex()
+			case index == 0:
+				indexConfig.Enabled = false
+				// storing the index takes a moment, the window goes on
+				stopping := files
+				go func() {
+					if err := stopping.Stop(); err != nil {
+						log.Println(err)
+					}
+				}()
+				files = nil
+			case index == 1 && files.Status().Paused:
+				files.Resume()
+			case index == 1:
+				files.Pause()
+			case index == 2:
+				files.Rebuild()
+			}
+
+			if index == 0 {
+				if err := indexConfig.Save(); err != nil {
+					log.Println(err)
+				}
+			}
+		},
+		func(gtx layout.Context, th *material.Theme) layout.Dimensions {
+			return material.H6(th, "Index").Layout(gtx)
+		},
+	)
+
+	menu.AddMenuItem(backMenuItem)
+	menu.AddMenuItem(forwardMenuItem)
+	menu.AddMenuItem(upMenuItem)
+	menu.AddMenuItem(historyMenuItem)
+	menu.AddMenuItem(pathMenuItem)
+	menu.AddMenuItem(searchMenuItem)
+	menu.AddMenuItem(grepMenuItem)
+	menu.AddMenuItem(filterMenuItem)
+	menu.AddMenuItem(filterOptionsMenuItem)
+	menu.AddMenuItem(newFolderMenuItem)
+	menu.AddMenuItem(editMenuItem)
+	menu.AddMenuItem(trashMenuItem)
+	menu.AddMenuItem(undoMenuItem)
+	menu.AddMenuItem(redoMenuItem)
+	menu.AddMenuItem(viewMenuItem)
+	menu.AddMenuItem(previewMenuItem)
+	menu.AddMenuItem(columnsMenuItem)
+	menu.AddMenuItem(sortMenuItem)
+	menu.AddMenuItem(groupMenuItem)
+	menu.AddMenuItem(indexMenuItem)
+
+	var ops op.Ops
 
 	for {
 		switch e := window.Event().(type) {
@@ -147,48 +955,247 @@ func run(window *app.Window) error {
 			// This graphics context is used for managing the rendering state.
 			gtx := app.NewContext(&ops, e)
 
+			// undo and redo shortcuts, a focused editor handles its own
+			for {
+				ev, ok := gtx.Source.Event(
+					key.Filter{
+						Name:     "Z",
+						Required: key.ModShortcut,
+						Optional: key.ModShift,
+					},
+				)
+				if !ok {
+					break
+				}
+
+				if e, ok := ev.(key.Event); ok && e.State == key.Press {
+					if e.Modifiers.Contain(key.ModShift) {
+						redo()
+					} else {
+						undo()
+					}
+				}
+			}
+
+			// select everything in the current folder
+			for {
+				ev, ok := gtx.Source.Event(
+					key.Filter{
+						Name:     "A",
+						Required: key.ModShortcut,
+					},
+				)
+				if !ok {
+					break
+				}
+
+				if e, ok := ev.(key.Event); ok && e.State == key.Press {
+					entries.Selection.SelectAll(entries.Entries)
+				}
+			}
+
+			// ctrl+h shows or hides the hidden files
+			for {
+				ev, ok := gtx.Event(key.Filter{Name: "H", Required: key.ModShortcut})
+				if !ok {
+					break
+				}
+
+				if e, ok := ev.(key.Event); ok && e.State == key.Press {
+					entries.Filter.ShowHidden = !entries.Filter.ShowHidden
+					entries.Prepare()
+				}
+			}
+
+			// F3 shows or hides the preview
+			for {
+				ev, ok := gtx.Event(key.Filter{Name: key.NameF3})
+				if !ok {
+					break
+				}
+
+				if e, ok := ev.(key.Event); ok && e.State == key.Press {
+					showPreview = !showPreview
+				}
+			}
+			if filePreview.Close.Clicked(gtx) {
+				showPreview = false
+			}
+
+			// a finished paste is journaled here, what was done before it
+			// stopped can be undone too
+			select {
+			case result := <-pasteDone:
+				cancelPaste()
+				cancelPaste = nil
+				if err := changes.RecordTransfers(result.kind, result.transfers); err != nil {
+					log.Println(err)
+				}
+
+				switch {
+				case errors.Is(result.err, context.Canceled):
+					status = "Paste canceled"
+				case result.err != nil:
+					status = result.err.Error()
+				default:
+					status = fmt.Sprintf("Pasted %d items", len(result.transfers))
+				}
+				if err := show(entries.Path); err != nil {
+					log.Println(err)
+				}
+			default:
+			}
+			if cancelPasteButton.Clicked(gtx) && cancelPaste != nil {
+				cancelPaste()
+			}
+
+			// the trash is only emptied once the user confirmed it
+			if cancelEmptyButton.Clicked(gtx) {
+				emptying = 0
+			}
+			if confirmEmptyButton.Clicked(gtx) && emptying > 0 {
+				emptying = 0
+				if err := trash.Empty(); err != nil {
+					status = err.Error()
+				}
+				if trash.IsTrash(entries.Path) {
+					if err := show(entries.Path); err != nil {
+						log.Println(err)
+					}
+				}
+			}
+
+			// F2 renames the selected entry
+			for {
+				ev, ok := gtx.Event(key.Filter{Name: key.NameF2})
+				if !ok {
+					break
+				}
+
+				if e, ok := ev.(key.Event); ok && e.State == key.Press {
+					startRename(gtx)
+				}
+			}
+
+			// enter in the rename box renames, escape gives up
+			for {
+				ev, ok := gtx.Event(key.Filter{Focus: renameEditor, Name: key.NameEscape})
+				if !ok {
+					break
+				}
+
+				if e, ok := ev.(key.Event); ok && e.State == key.Press && renaming != "" {
+					renaming = ""
+					entries.Focus(gtx)
+				}
+			}
+			for {
+				ev, ok := renameEditor.Update(gtx)
+				if !ok {
+					break
+				}
+
+				if _, ok := ev.(widget.SubmitEvent); !ok || renaming == "" {
+					continue
+				}
+				renamed, err := operations.Rename(renaming, renameEditor.Text())
+				if err != nil {
+					status = err.Error()
+				} else if renamed != renaming {
+					if err := changes.Record(journal.KindRename, []string{renaming}, []string{renamed}); err != nil {
+						log.Println(err)
+					}
+					if err := show(entries.Path); err != nil {
+						log.Println(err)
+					}
+				}
+				renaming = ""
+				entries.Focus(gtx)
+			}
+
+			// enter in the search box searches under the current folder
+			for {
+				ev, ok := searchEditor.Update(gtx)
+				if !ok {
+					break
+				}
+
+				if _, ok := ev.(widget.SubmitEvent); ok && searchEditor.Text() != "" {
+					startSearch(searchEditor.Text())
+					entries.Focus(gtx)
+				}
+			}
+
+			select {
+			case result := <-searchDone:
+				// a search stopped by moving on has nothing to report
+				if result.ctx.Err() == nil {
+					cancelSearch()
+					if result.err != nil {
+						status = result.err.Error()
+					} else {
+						status = fmt.Sprintf("Search finished, %d results", result.found)
+					}
+				}
+			case result := <-grepDone:
+				if result.ctx.Err() == nil {
+					cancelGrep()
+					if result.err != nil {
+						status = result.err.Error()
+					} else {
+						status = fmt.Sprintf("Found %d matching lines", findings.Len())
+					}
+				}
+			default:
+			}
+
+			for {
+				ev, ok := grepEditor.Update(gtx)
+				if !ok {
+					break
+				}
+
+				if _, ok := ev.(widget.SubmitEvent); ok && grepEditor.Text() != "" {
+					startGrep(grepEditor.Text())
+				}
+			}
+			if findings.Close.Clicked(gtx) {
+				cancelGrep()
+				showFindings = false
+			}
+
+			// the filter is applied on every keystroke, the folder is not
+			// read again
+			for {
+				ev, ok := filterEditor.Update(gtx)
+				if !ok {
+					break
+				}
+
+				if _, ok := ev.(widget.ChangeEvent); ok {
+					entries.Filter.Pattern = filterEditor.Text()
+					entries.Prepare()
+					if err := entries.Filter.Err(); err != nil {
+						status = err.Error()
+					}
+				}
+			}
+
 			// Process events that arrived between the last frame and this one.
-			// for {
-			// 	// wait for any keyboard input
-			// 	_, ok := e.Source.Event(
-			// 		key.Filter{
-			// 			Name: key.NameReturn,
-			// 		},
-			// 	)
-			// 	if !ok {
-			// 		break
-			// 	}
-
-			// 	// if the path editor is focused, update the path
-			// 	entrys, err := entry.ReadPath(pathEditor.Text())
-			// 	if err != nil {
-			// 		log.Println(err)
-			// 		break
-			// 	}
-			// 	entries.Update(entrys)
-			// }
-
-			// layout.Flex{
-			// 	Axis: layout.Vertical,
-			// }.Layout(gtx,
-			// 	layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-			// 		return menu.Layout(gtx, theme)
-			// 	}),
-			// 	layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
-			// 		layoutEntries, updatedEntries, err := entries.Layout(gtx, theme, watcher)
-			// 		if err != nil {
-			// 			log.Println(err)
-			// 			return layout.Dimensions{}
-			// 		}
-
-			// 		if updatedEntries != nil {
-			// 			entries.Update(updatedEntries)
-			// 			pathEditor.SetText(updatedEntries.Path)
-			// 		}
-
-			// 		return layoutEntries
-			// 	}),
-			// )
+			for {
+				ev, ok := pathEditor.Update(gtx)
+				if !ok {
+					break
+				}
+
+				// enter in the path editor goes to the typed path
+				if _, ok := ev.(widget.SubmitEvent); ok {
+					navigate(pathEditor.Text())
+					entries.Focus(gtx)
+				}
+			}
+
+			updatePreview()
 
 			layout.Background{}.Layout(gtx,
 				func(gtx layout.Context) layout.Dimensions {
@@ -197,12 +1204,135 @@ func run(window *app.Window) error {
 					return layout.Dimensions{Size: gtx.Constraints.Min}
 				},
 				func(gtx layout.Context) layout.Dimensions {
-					return button.Layout(gtx, theme)
+					return layout.Flex{
+						Axis: layout.Vertical,
+					}.Layout(gtx,
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							return menu.Layout(gtx, theme)
+						}),
+						layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+							return layout.Flex{
+								Axis: layout.Horizontal,
+							}.Layout(gtx,
+								layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+									layoutEntries, updatedEntries, err := entries.Layout(gtx, theme, watcher)
+									if err != nil {
+										log.Println(err)
+										return layout.Dimensions{}
+									}
+
+									if updatedEntries != nil {
+										cancelSearch()
+										clearFilter()
+										entries.Update(updatedEntries)
+										pathEditor.SetText(updatedEntries.Path)
+										history.Visit(updatedEntries.Path)
+									}
+
+									return layoutEntries
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									if !showPreview {
+										return layout.Dimensions{}
+									}
+
+									// the preview has a fixed width right of the entries
+									width := gtx.Dp(unit.Dp(360))
+									gtx.Constraints.Min.X = width
+									gtx.Constraints.Max.X = width
+									gtx.Constraints.Min.Y = gtx.Constraints.Max.Y
+									paint.FillShape(gtx.Ops, theme.Palette.ContrastBg, clip.Rect{Max: image.Point{X: 1, Y: gtx.Constraints.Max.Y}}.Op())
+									return layout.Inset{Left: unit.Dp(1)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+										return filePreview.Layout(gtx, theme)
+									})
+								}),
+							)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if !showFindings {
+								return layout.Dimensions{}
+							}
+
+							// the results pane has a fixed height under the entries
+							height := gtx.Dp(unit.Dp(240))
+							gtx.Constraints.Min.Y = height
+							gtx.Constraints.Max.Y = height
+							paint.FillShape(gtx.Ops, theme.Palette.ContrastBg, clip.Rect{Max: image.Point{X: gtx.Constraints.Max.X, Y: 1}}.Op())
+							return findings.Layout(gtx, theme)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if cancelPaste == nil {
+								return layout.Dimensions{}
+							}
+
+							pasteLock.Lock()
+							progress := pasteProgress
+							pasteLock.Unlock()
+
+							label := "Pasting..."
+							if progress.Source != "" {
+								label = fmt.Sprintf("Pasting %s", filepath.Base(progress.Source))
+							}
+							return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+								return layout.Flex{
+									Axis:      layout.Horizontal,
+									Alignment: layout.Middle,
+								}.Layout(gtx,
+									layout.Rigid(material.Body2(theme, label).Layout),
+									layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+										return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(8)}.Layout(gtx, material.ProgressBar(theme, progress.Fraction()).Layout)
+									}),
+									layout.Rigid(material.Button(theme, cancelPasteButton, "Cancel").Layout),
+								)
+							})
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if emptying == 0 {
+								return layout.Dimensions{}
+							}
+
+							question := fmt.Sprintf("Permanently delete the %d items in the trash?", emptying)
+							if emptying == 1 {
+								question = "Permanently delete the item in the trash?"
+							}
+							return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+								return layout.Flex{
+									Axis:      layout.Horizontal,
+									Alignment: layout.Middle,
+								}.Layout(gtx,
+									layout.Flexed(1, material.Body2(theme, question).Layout),
+									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+										return layout.Inset{Right: unit.Dp(8)}.Layout(gtx, material.Button(theme, confirmEmptyButton, "Empty Trash").Layout)
+									}),
+									layout.Rigid(material.Button(theme, cancelEmptyButton, "Cancel").Layout),
+								)
+							})
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if renaming == "" {
+								return layout.Dimensions{}
+							}
+
+							// the new name is typed under the entries
+							return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+								return layout.Flex{
+									Axis:      layout.Horizontal,
+									Alignment: layout.Middle,
+								}.Layout(gtx,
+									layout.Rigid(material.Body2(theme, fmt.Sprintf("Rename %s to ", filepath.Base(renaming))).Layout),
+									layout.Flexed(1, material.Editor(theme, renameEditor, "New name").Layout),
+								)
+							})
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if status == "" {
+								return layout.Dimensions{}
+							}
+							return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Body2(theme, status).Layout)
+						}),
+					)
 				},
 			)
-			if button.NumOfClicks() > 0 {
-				log.Println("Button clicked", button.NumOfClicks(), "times")
-			}
 
 			// Pass the drawing operations to the GPU.
 			e.Frame(gtx.Ops)
@@ -2,31 +2,53 @@ package assets
 
 import (
 	"bytes"
+	"embed"
 	"image"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+
+	// decoders of the image formats of the assets
+	_ "image/png"
 )
 
+// files are the assets built into the binary, so it runs from anywhere.
+//
+//go:embed files
+var files embed.FS
+
+// OverrideEnv is the environment variable naming a folder whose files are
+// used instead of the built in ones.
+const OverrideEnv = "GOLE_ASSETS"
+
+// override is the folder searched for assets before the built in ones, empty
+// for none.
+var override = os.Getenv(OverrideEnv)
+
+// Root returns the folder searched for assets before the built in ones, or
+// "" if there is none.
 func Root() string {
-	return "assets"
+	return override
 }
 
-func GetAsset(path string) (*[]byte, error) {
-	// read file from assets
-	// return file content
-	assetFile, err := os.Open(filepath.Join(Root(), path))
-	if err != nil {
-		return nil, err
-	}
-	defer assetFile.Close()
-
-	// get the file size
-	fileInfo, _ := assetFile.Stat()
+// SetRoot makes the assets found in dir take precedence over the built in
+// ones, for themes and development. An empty dir only uses the built in
+// assets. It has to be called before any asset is used, as icons are cached.
+func SetRoot(dir string) {
+	override = dir
+}
 
-	// read the file content
-	fileContent := make([]byte, fileInfo.Size())
-	_, err = assetFile.Read(fileContent)
+func GetAsset(name string) (*[]byte, error) {
+	// an asset of the override folder replaces the built in one
+	if override != "" {
+		fileContent, err := os.ReadFile(filepath.Join(override, name))
+		if err == nil {
+			return &fileContent, nil
+		}
+	}
 
+	fileContent, err := fs.ReadFile(files, path.Join("files", filepath.ToSlash(name)))
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +65,19 @@ func GetImage(path string) (*image.Image, error) {
 		return nil, err
 	}
 
-	img, _, err := image.Decode(bytes.NewReader(*fileContent))
+	return decodeImage(path, *fileContent)
+}
+
+// decodeImage decodes the image file named name with content. SVG images are
+// drawn at the size they say they are.
+func decodeImage(name string, content []byte) (*image.Image, error) {
+	var img image.Image
+	var err error
+	if isSVG(name) {
+		img, err = rasterizeSVG(content, 0)
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(content))
+	}
 	if err != nil {
 		return nil, err
 	}
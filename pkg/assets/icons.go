@@ -0,0 +1,185 @@
+package assets
+
+import (
+	"image"
+	"os"
+	"sync"
+
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"golang.org/x/image/draw"
+)
+
+// maxImageOps is how many scaled image ops are kept before they are all
+// dropped and made again as they are needed.
+const maxImageOps = 1024
+
+// icons keeps every image asset decoded once, and the image ops drawing
+// them at the sizes they were asked for. It is safe for concurrent use.
+var icons = struct {
+	mu     sync.Mutex
+	images map[string]*image.Image
+	errs   map[string]error
+	ops    map[opKey]paint.ImageOp
+	// vectors are the SVG files of the images that are drawn again at each
+	// size instead of scaled
+	vectors map[*image.Image][]byte
+}{
+	images:  map[string]*image.Image{},
+	errs:    map[string]error{},
+	ops:     map[opKey]paint.ImageOp{},
+	vectors: map[*image.Image][]byte{},
+}
+
+// opKey is an image at a size in pixels.
+type opKey struct {
+	img  *image.Image
+	size int
+}
+
+// Icon returns the image asset at path like GetImage, but decodes it only
+// the first time. The image is shared by every caller and must not be
+// changed.
+func Icon(path string) (*image.Image, error) {
+	return cachedImage(path, func() (*image.Image, error) {
+		content, err := GetAsset(path)
+		if err != nil {
+			return nil, err
+		}
+		return decodeIcon(path, *content)
+	})
+}
+
+// iconFile returns the image of the icon file at path, decoded once like
+// the ones of Icon.
+func iconFile(path string) (*image.Image, error) {
+	return cachedImage(path, func() (*image.Image, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return decodeIcon(path, content)
+	})
+}
+
+// decodeIcon decodes the icon file named name with content. The content of
+// SVG icons is kept for ImageOp to draw them at the exact size they are
+// shown at. It is called with icons.mu held.
+func decodeIcon(name string, content []byte) (*image.Image, error) {
+	img, err := decodeImage(name, content)
+	if err != nil {
+		return nil, err
+	}
+	if isSVG(name) {
+		icons.vectors[img] = content
+	}
+	return img, nil
+}
+
+// cachedImage returns the image kept for key, made with load the first time.
+func cachedImage(key string, load func() (*image.Image, error)) (*image.Image, error) {
+	icons.mu.Lock()
+	defer icons.mu.Unlock()
+
+	if img, ok := icons.images[key]; ok {
+		return img, nil
+	}
+	if err, ok := icons.errs[key]; ok {
+		return nil, err
+	}
+
+	img, err := load()
+	if err != nil {
+		icons.errs[key] = err
+		return nil, err
+	}
+	icons.images[key] = img
+	return img, nil
+}
+
+// ImageOp returns the op drawing img with its longest side size pixels long.
+// The image is scaled down with a smooth filter the first time, and the op
+// is kept so the same texture is drawn frame after frame. Raster images are
+// never scaled up, a size of 0 keeps them as they are. SVG icons are drawn
+// again at exactly size pixels, so they are sharp at any scale.
+func ImageOp(img *image.Image, size int) paint.ImageOp {
+	key := opKey{img: img, size: size}
+
+	icons.mu.Lock()
+	op, ok := icons.ops[key]
+	icons.mu.Unlock()
+	if ok {
+		return op
+	}
+
+	op = NewImageOp(img, size)
+
+	icons.mu.Lock()
+	if len(icons.ops) >= maxImageOps {
+		icons.ops = map[opKey]paint.ImageOp{}
+	}
+	icons.ops[key] = op
+	icons.mu.Unlock()
+	return op
+}
+
+// NewImageOp returns the op drawing img with its longest side size pixels
+// long like ImageOp does, but doesn't keep it. It is for images that come and
+// go like thumbnails, whose ops are kept by their owner.
+func NewImageOp(img *image.Image, size int) paint.ImageOp {
+	icons.mu.Lock()
+	vector := icons.vectors[img]
+	icons.mu.Unlock()
+
+	scaled := scale(*img, size)
+	if vector != nil && size > 0 {
+		if rasterized, err := rasterizeSVG(vector, size); err == nil {
+			scaled = rasterized
+		}
+	}
+	return paint.NewImageOp(scaled)
+}
+
+// LayoutIcon draws img with its longest side size long. The image op is made
+// for the pixels size is at the scale of gtx, so the icon is drawn sharp
+// rather than scaled by the GPU.
+func LayoutIcon(gtx layout.Context, img *image.Image, size unit.Dp) layout.Dimensions {
+	if img == nil {
+		return layout.Dimensions{}
+	}
+	return LayoutImageOp(gtx, ImageOp(img, gtx.Dp(size)), size)
+}
+
+// LayoutImageOp draws src with its longest side size long, like LayoutIcon
+// for an op made for the pixels size is at the scale of gtx.
+func LayoutImageOp(gtx layout.Context, src paint.ImageOp, size unit.Dp) layout.Dimensions {
+	pixels := gtx.Dp(size)
+	longest := max(src.Size().X, src.Size().Y)
+	if longest == 0 || gtx.Metric.PxPerDp == 0 {
+		return layout.Dimensions{}
+	}
+
+	return widget.Image{
+		Src: src,
+		// image pixels to dp, so the longest side is pixels long
+		Scale: float32(pixels) / float32(longest) / gtx.Metric.PxPerDp,
+	}.Layout(gtx)
+}
+
+// scale returns img with its longest side size pixels long, or img itself
+// if it is not larger than that.
+func scale(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	longest := max(bounds.Dx(), bounds.Dy())
+	if size <= 0 || longest <= size {
+		return img
+	}
+
+	width := max(bounds.Dx()*size/longest, 1)
+	height := max(bounds.Dy()*size/longest, 1)
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
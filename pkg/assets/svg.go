@@ -0,0 +1,52 @@
+package assets
+
+import (
+	"bytes"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// defaultSVGSize is the size in pixels SVG images that don't tell theirs are
+// drawn at.
+const defaultSVGSize = 128
+
+// isSVG tells if the file named name is an SVG image.
+func isSVG(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".svg")
+}
+
+// rasterizeSVG draws the SVG image in content with its longest side size
+// pixels long, keeping its aspect ratio. A size of 0 draws it at the size it
+// says it is.
+func rasterizeSVG(content []byte, size int) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	if size <= 0 {
+		size = int(max(icon.ViewBox.W, icon.ViewBox.H) + 0.5)
+	}
+	if size <= 0 {
+		size = defaultSVGSize
+	}
+
+	width, height := size, size
+	if w, h := icon.ViewBox.W, icon.ViewBox.H; w > 0 && h > 0 {
+		if w > h {
+			height = max(int(float64(size)*h/w+0.5), 1)
+		} else {
+			width = max(int(float64(size)*w/h+0.5), 1)
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	icon.SetTarget(0, 0, float64(width), float64(height))
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1)
+	return img, nil
+}
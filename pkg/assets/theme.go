@@ -0,0 +1,445 @@
+package assets
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IconThemeEnv is the environment variable naming the icon theme to use
+// instead of the one of the desktop.
+const IconThemeEnv = "GOLE_ICON_THEME"
+
+// fallbackTheme is the theme every theme falls back to, as it has the icons
+// of the applications.
+const fallbackTheme = "hicolor"
+
+// ErrNoThemeIcon is returned when no installed icon theme has any of the
+// icons asked for.
+var ErrNoThemeIcon = errors.New("no icon in the icon themes")
+
+// iconExtensions are the formats of theme icons that are loaded, the
+// preferred first.
+var iconExtensions = []string{".png", ".svg"}
+
+// Theme is an icon theme of the freedesktop.org icon theme specification,
+// read from its index.theme.
+type Theme struct {
+	Name string
+	// Inherits are the themes icons missing from this one are taken from
+	Inherits []string
+	dirs     []themeDir
+}
+
+// themeDir is a folder of a theme, with icons all made for the same sizes.
+type themeDir struct {
+	path      string
+	size      int
+	scale     int
+	minSize   int
+	maxSize   int
+	threshold int
+	// kind is Fixed, Scalable or Threshold
+	kind string
+}
+
+// themes keeps the themes read and the icons found in them. It is safe for
+// concurrent use.
+var themes = struct {
+	mu sync.Mutex
+	// name is the icon theme in use, "" until it is first looked up
+	name   string
+	themes map[string]*Theme
+	// files are the names of the files in each folder of a theme
+	files map[string]map[string]bool
+	found map[lookupKey]string
+}{
+	themes: map[string]*Theme{},
+	files:  map[string]map[string]bool{},
+	found:  map[lookupKey]string{},
+}
+
+// lookupKey is the icons looked for at a size and scale.
+type lookupKey struct {
+	names string
+	size  int
+	scale int
+}
+
+// IconTheme returns the name of the icon theme in use: the one set with
+// SetIconTheme or in IconThemeEnv, else the one of the desktop.
+func IconTheme() string {
+	themes.mu.Lock()
+	defer themes.mu.Unlock()
+	return iconTheme()
+}
+
+// SetIconTheme makes the icons be looked up in the theme called name, an
+// empty name going back to the theme of the desktop.
+func SetIconTheme(name string) {
+	themes.mu.Lock()
+	defer themes.mu.Unlock()
+	themes.name = name
+	themes.found = map[lookupKey]string{}
+}
+
+func iconTheme() string {
+	if themes.name == "" {
+		themes.name = os.Getenv(IconThemeEnv)
+	}
+	if themes.name == "" {
+		themes.name = desktopIconTheme()
+	}
+	return themes.name
+}
+
+// LookupIcon returns the path of the file of the first of names found in the
+// icon theme in use, the themes it inherits, or hicolor, the file made for
+// the closest size in pixels at scale. It returns "" if there is none.
+func LookupIcon(names []string, size int, scale int) string {
+	themes.mu.Lock()
+	defer themes.mu.Unlock()
+
+	key := lookupKey{names: strings.Join(names, ","), size: size, scale: scale}
+	if path, ok := themes.found[key]; ok {
+		return path
+	}
+
+	path := ""
+	for _, theme := range themeChain(iconTheme()) {
+		if path = theme.lookup(names, size, scale); path != "" {
+			break
+		}
+	}
+	if path == "" {
+		path = lookupUnthemed(names)
+	}
+	themes.found[key] = path
+	return path
+}
+
+// ThemeIcon returns the image of the first of names found in the icon
+// themes, the one made for the closest size to size at scale, so the icons
+// made for screens of twice the density are used at a scale of 2. Images are
+// decoded once and shared by every caller like the ones of Icon.
+func ThemeIcon(names []string, size int, scale int) (*image.Image, error) {
+	path := LookupIcon(names, size, max(scale, 1))
+	if path == "" {
+		return nil, ErrNoThemeIcon
+	}
+	return iconFile(path)
+}
+
+// themeChain returns the theme called name, the themes it inherits from, the
+// closest first, and hicolor last. Themes that are not installed are left
+// out.
+func themeChain(name string) []*Theme {
+	var chain []*Theme
+	seen := map[string]bool{}
+
+	var add func(name string)
+	add = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		theme := loadTheme(name)
+		if theme == nil {
+			return
+		}
+		chain = append(chain, theme)
+		for _, parent := range theme.Inherits {
+			add(parent)
+		}
+	}
+	add(name)
+	add(fallbackTheme)
+	return chain
+}
+
+// lookup returns the path of the first of names in the theme, preferring
+// an icon made for the size to the first of names.
+func (t *Theme) lookup(names []string, size int, scale int) string {
+	for _, name := range names {
+		if path := t.lookupIcon(name, size, scale); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// lookupIcon returns the path of the icon called name in a folder made for
+// size at scale, or else in the folder made for the closest size.
+func (t *Theme) lookupIcon(name string, size int, scale int) string {
+	for _, dir := range t.dirs {
+		if !dir.matches(size, scale) {
+			continue
+		}
+		if path := findIcon(dir.path, name); path != "" {
+			return path
+		}
+	}
+
+	closest, distance := "", math.MaxInt
+	for _, dir := range t.dirs {
+		d := dir.distance(size, scale)
+		if d >= distance {
+			continue
+		}
+		if path := findIcon(dir.path, name); path != "" {
+			closest, distance = path, d
+		}
+	}
+	return closest
+}
+
+// matches tells if the icons of the folder are made for size at scale.
+func (d themeDir) matches(size int, scale int) bool {
+	if d.scale != scale {
+		return false
+	}
+	switch d.kind {
+	case "Fixed":
+		return d.size == size
+	case "Scalable":
+		return d.minSize <= size && size <= d.maxSize
+	default:
+		return d.size-d.threshold <= size && size <= d.size+d.threshold
+	}
+}
+
+// distance tells how far the icons of the folder are from size at scale, in
+// pixels on the screen.
+func (d themeDir) distance(size int, scale int) int {
+	pixels := size * scale
+	switch d.kind {
+	case "Fixed":
+		return abs(d.size*d.scale - pixels)
+	case "Scalable":
+		if pixels < d.minSize*d.scale {
+			return d.minSize*d.scale - pixels
+		}
+		if pixels > d.maxSize*d.scale {
+			return pixels - d.maxSize*d.scale
+		}
+		return 0
+	default:
+		if pixels < (d.size-d.threshold)*d.scale {
+			return d.minSize*d.scale - pixels
+		}
+		if pixels > (d.size+d.threshold)*d.scale {
+			return pixels - d.maxSize*d.scale
+		}
+		return 0
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// findIcon returns the path of the icon called name in the folder dir, or
+// "".
+func findIcon(dir string, name string) string {
+	files, ok := themes.files[dir]
+	if !ok {
+		files = map[string]bool{}
+		if entries, err := os.ReadDir(dir); err == nil {
+			for _, entry := range entries {
+				files[entry.Name()] = true
+			}
+		}
+		themes.files[dir] = files
+	}
+
+	for _, ext := range iconExtensions {
+		if files[name+ext] {
+			return filepath.Join(dir, name+ext)
+		}
+	}
+	return ""
+}
+
+// lookupUnthemed returns the path of the first of names found outside of
+// any theme, in the base folders and /usr/share/pixmaps, or "".
+func lookupUnthemed(names []string) string {
+	dirs := append(iconBaseDirs(), "/usr/share/pixmaps")
+	for _, name := range names {
+		for _, dir := range dirs {
+			if path := findIcon(dir, name); path != "" {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// iconBaseDirs returns the folders themes are installed in, the ones of the
+// user first.
+func iconBaseDirs() []string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".icons"))
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range append([]string{dataHome}, filepath.SplitList(dataDirs)...) {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "icons"))
+		}
+	}
+	return dirs
+}
+
+// loadTheme returns the theme called name, or nil if it is not installed.
+// The index.theme of the first base folder having one is read, the folders
+// of the theme are looked for in every base folder.
+func loadTheme(name string) *Theme {
+	if theme, ok := themes.themes[name]; ok {
+		return theme
+	}
+
+	var theme *Theme
+	bases := iconBaseDirs()
+	for _, base := range bases {
+		sections, err := readIni(filepath.Join(base, name, "index.theme"))
+		if err != nil {
+			continue
+		}
+		theme = newTheme(name, sections, bases)
+		break
+	}
+	themes.themes[name] = theme
+	return theme
+}
+
+// newTheme makes the theme called name out of the sections of its
+// index.theme.
+func newTheme(name string, sections map[string]map[string]string, bases []string) *Theme {
+	main := sections["Icon Theme"]
+	theme := &Theme{Name: name, Inherits: splitList(main["Inherits"])}
+
+	dirs := append(splitList(main["Directories"]), splitList(main["ScaledDirectories"])...)
+	for _, dir := range dirs {
+		section, ok := sections[dir]
+		if !ok {
+			continue
+		}
+		size := atoi(section["Size"], 0)
+		if size <= 0 {
+			continue
+		}
+
+		d := themeDir{
+			size:      size,
+			scale:     atoi(section["Scale"], 1),
+			minSize:   atoi(section["MinSize"], size),
+			maxSize:   atoi(section["MaxSize"], size),
+			threshold: atoi(section["Threshold"], 2),
+			kind:      section["Type"],
+		}
+		// the folder may be split over the base folders
+		for _, base := range bases {
+			d.path = filepath.Join(base, name, dir)
+			if _, err := os.Stat(d.path); err == nil {
+				theme.dirs = append(theme.dirs, d)
+			}
+		}
+	}
+	return theme
+}
+
+// desktopIconTheme returns the icon theme set in the settings of GTK or KDE,
+// or hicolor if none is.
+func desktopIconTheme() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return fallbackTheme
+	}
+
+	for _, settings := range []string{"gtk-4.0", "gtk-3.0"} {
+		sections, err := readIni(filepath.Join(config, settings, "settings.ini"))
+		if err == nil && sections["Settings"]["gtk-icon-theme-name"] != "" {
+			return sections["Settings"]["gtk-icon-theme-name"]
+		}
+	}
+	if sections, err := readIni(filepath.Join(config, "kdeglobals")); err == nil && sections["Icons"]["Theme"] != "" {
+		return sections["Icons"]["Theme"]
+	}
+	return fallbackTheme
+}
+
+// readIni reads the file at path made of [section] headers and key=value
+// lines, like index.theme.
+func readIni(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := line[1 : len(line)-1]
+			section = sections[name]
+			if section == nil {
+				section = map[string]string{}
+				sections[name] = section
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if found && section != nil {
+			section[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// splitList returns the items of a comma separated list.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// atoi returns the number in s, or fallback if there is none.
+func atoi(s string, fallback int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,55 @@
+package assets
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThemeIconScale(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("HOME", data)
+	t.Setenv("XDG_DATA_HOME", data)
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(data, "none"))
+
+	// a theme with the same folder icon for normal and double density
+	theme := filepath.Join(data, "icons", "scaled")
+	index := "[Icon Theme]\nName=Scaled\nDirectories=48x48/places,48x48@2/places\n\n" +
+		"[48x48/places]\nSize=48\nType=Fixed\n\n" +
+		"[48x48@2/places]\nSize=48\nScale=2\nType=Fixed\n"
+	if err := os.MkdirAll(theme, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(theme, "index.theme"), []byte(index), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for dir, size := range map[string]int{"48x48/places": 48, "48x48@2/places": 96} {
+		if err := os.MkdirAll(filepath.Join(theme, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Create(filepath.Join(theme, dir, "folder.png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = png.Encode(file, image.NewNRGBA(image.Rect(0, 0, size, size)))
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetIconTheme("scaled")
+	defer SetIconTheme("")
+
+	for scale, want := range map[int]int{1: 48, 2: 96} {
+		icon, err := ThemeIcon([]string{"folder"}, 48, scale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (*icon).Bounds().Dx(); got != want {
+			t.Errorf("scale %d: got an icon of %d pixels, want %d", scale, got, want)
+		}
+	}
+}
@@ -1,19 +1,28 @@
 package explorer
 
 import (
+	"errors"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/ShakedGold/Gole/pkg/widgets/entry"
 	"github.com/fsnotify/fsnotify"
 )
 
+// Home returns the entries of the home folder, they are read once loaded.
 func Home() (*entry.Entries, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return &entry.Entries{}, err
 	}
-	return entry.ReadPath(homeDir)
+
+	entries, err := entry.Open(homeDir)
+	if err != nil {
+		return &entry.Entries{}, err
+	}
+	return entries, nil
 }
 
 // Watcher watches for changes in the filesystem
@@ -31,27 +40,78 @@ func Watcher(path string) (*fsnotify.Watcher, error) {
 	return watcher, nil
 }
 
+const (
+	// settle is how long Watch waits for more events before applying them
+	settle = 100 * time.Millisecond
+	// maxDelay is how long events wait at most while more keep coming
+	maxDelay = time.Second
+)
+
+// Watch keeps entries up to date with the folders watcher watches until the
+// watcher is closed. Events are collected until they settle, so a burst of
+// them, like a large copy, reads each changed file once. The changes are
+// applied to entries on their next layout, which is requested.
 func Watch(watcher *fsnotify.Watcher, entries *entry.Entries) {
+	// changed holds the names of the changed files by folder
+	changed := map[string]map[string]bool{}
+	overflow := false
+
+	var wait <-chan time.Time
+	var first, last time.Time
+	schedule := func() {
+		last = time.Now()
+		if wait == nil {
+			first = last
+			wait = time.After(settle)
+		}
+	}
+
 	for {
 		select {
-		case _, ok := <-watcher.Events:
+		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
 
-			// if there is a change update the entries
-			entries, _ = entry.ReadPath(entries.Path)
-			newEntries, err := entries.Prepare()
-			if err != nil {
-				log.Println(err)
+			folder, name := filepath.Split(event.Name)
+			folder = filepath.Clean(folder)
+			if changed[folder] == nil {
+				changed[folder] = map[string]bool{}
 			}
-			entries = newEntries
+			changed[folder][name] = true
+			schedule()
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
 			}
 
 			log.Println("Error:", err)
+			// events were dropped, only reading everything again is right
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				overflow = true
+				schedule()
+			}
+		case now := <-wait:
+			// wait some more while events keep coming, but not forever
+			if remaining := last.Add(settle).Sub(now); remaining > 0 && now.Sub(first) < maxDelay {
+				wait = time.After(remaining)
+				continue
+			}
+			wait = nil
+
+			if overflow {
+				entries.Reload()
+			} else {
+				for folder, names := range changed {
+					list := make([]string, 0, len(names))
+					for name := range names {
+						list = append(list, name)
+					}
+					entries.Refresh(folder, list)
+				}
+			}
+			changed = map[string]map[string]bool{}
+			overflow = false
 		}
 	}
 }
@@ -0,0 +1,190 @@
+package fileops
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// measure counts the files and bytes below path.
+func measure(path string) (int, int64, error) {
+	files := 0
+	var size int64
+
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		files++
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return files, size, err
+}
+
+// copy copies source to target, recursing into folders. Permissions and
+// modification times are kept.
+func (e *Engine) copy(ctx context.Context, source string, target string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+
+	e.progress.Source = source
+	e.progress.Destination = target
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		// the times of a link can't be set portably, only its target's
+		return e.copySymlink(source, target)
+	case info.IsDir():
+		err = e.copyDir(ctx, source, target, info)
+	default:
+		err = e.copyFile(ctx, source, target, info)
+	}
+	if err != nil {
+		return err
+	}
+
+	// folders get their time after their content was written into them
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+func (e *Engine) copyDir(ctx context.Context, source string, target string, info fs.FileInfo) error {
+	targetInfo, err := os.Lstat(target)
+	switch {
+	case err == nil && !targetInfo.IsDir():
+		// a file is in the way of the folder
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+		fallthrough
+	case errors.Is(err, os.ErrNotExist):
+		// keep the folder writable until its content was copied
+		if err := os.Mkdir(target, info.Mode().Perm()|0o700); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+	e.advance(0, 1)
+
+	children, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		childTarget, err := e.resolve(filepath.Join(source, child.Name()), filepath.Join(target, child.Name()))
+		if err != nil {
+			return err
+		}
+		if childTarget == "" {
+			continue
+		}
+
+		err = e.copy(ctx, filepath.Join(source, child.Name()), childTarget)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Chmod(target, info.Mode().Perm())
+}
+
+func (e *Engine) copyFile(ctx context.Context, source string, target string, info fs.FileInfo) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// replace instead of truncating so hard links to the old file stay intact
+	if targetInfo, err := os.Lstat(target); err == nil {
+		if targetInfo.IsDir() {
+			err = os.RemoveAll(target)
+		} else {
+			err = os.Remove(target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, &progressReader{ctx: ctx, reader: in, engine: e})
+	if err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+	e.advance(0, 1)
+
+	// the umask may have stripped some bits on create
+	return os.Chmod(target, info.Mode().Perm())
+}
+
+func (e *Engine) copySymlink(source string, target string) error {
+	link, err := os.Readlink(source)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(target); err == nil {
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Symlink(link, target); err != nil {
+		return err
+	}
+	e.advance(0, 1)
+
+	return nil
+}
+
+// progressReader reports the bytes read through it and stops once the
+// context is canceled.
+type progressReader struct {
+	ctx    context.Context
+	reader io.Reader
+	engine *Engine
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.engine.advance(int64(n), 0)
+	}
+
+	return n, err
+}
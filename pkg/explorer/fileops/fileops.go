@@ -0,0 +1,443 @@
+package fileops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Conflict tells the engine what to do when the destination already exists.
+type Conflict int
+
+const (
+	// Skip leaves the existing destination alone
+	Skip Conflict = iota
+	// Overwrite replaces the existing destination, folders are merged
+	Overwrite
+	// KeepBoth picks a new name such as "name (1).txt" for the destination
+	KeepBoth
+)
+
+// Kind is the kind of operation being reported.
+type Kind int
+
+const (
+	KindCopy Kind = iota
+	KindMove
+	KindRename
+	KindDelete
+	KindCreateFile
+	KindCreateFolder
+)
+
+var (
+	ErrInvalidName = errors.New("invalid file name")
+	ErrIntoItself  = errors.New("cannot copy or move a folder into itself")
+	ErrOntoParent  = errors.New("cannot copy or move an item onto a folder it is in")
+)
+
+// Progress describes how far a running operation got.
+type Progress struct {
+	Kind Kind
+	// Source and Destination are the paths currently being processed
+	Source      string
+	Destination string
+	// Bytes are counted for copies, Files for every operation
+	Bytes      int64
+	TotalBytes int64
+	Files      int
+	TotalFiles int
+}
+
+// Fraction returns the progress between 0 and 1.
+func (p Progress) Fraction() float32 {
+	if p.TotalBytes > 0 {
+		return float32(p.Bytes) / float32(p.TotalBytes)
+	}
+	if p.TotalFiles > 0 {
+		return float32(p.Files) / float32(p.TotalFiles)
+	}
+	return 0
+}
+
+// Transfer is a single source that was copied or moved to Destination.
+type Transfer struct {
+	Source      string
+	Destination string
+}
+
+// Engine performs file operations on the filesystem.
+type Engine struct {
+	// Conflict is the default conflict policy
+	Conflict Conflict
+	// Resolve, if set, is asked about every conflict instead of using Conflict
+	Resolve func(source, destination string) Conflict
+	// OnProgress, if set, is called while operations are running
+	OnProgress func(Progress)
+
+	progress Progress
+}
+
+// NewEngine creates an engine with the given conflict policy.
+func NewEngine(conflict Conflict) *Engine {
+	return &Engine{
+		Conflict: conflict,
+	}
+}
+
+// Copy copies sources into the folder destination and returns where each of
+// them went. Sources that were skipped because of a conflict are left out.
+func (e *Engine) Copy(ctx context.Context, sources []string, destination string) ([]Transfer, error) {
+	if err := e.start(KindCopy, sources); err != nil {
+		return nil, err
+	}
+
+	var created []Transfer
+	for _, source := range sources {
+		target, err := e.target(source, destination)
+		if err != nil {
+			return created, err
+		}
+		if target == "" {
+			continue
+		}
+
+		err = e.copy(ctx, source, target)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, Transfer{Source: source, Destination: target})
+	}
+
+	return created, nil
+}
+
+// Move moves sources into the folder destination and returns where each of
+// them went. Moves across filesystems fall back to copy and delete.
+func (e *Engine) Move(ctx context.Context, sources []string, destination string) ([]Transfer, error) {
+	if err := e.start(KindMove, sources); err != nil {
+		return nil, err
+	}
+
+	var moved []Transfer
+	for _, source := range sources {
+		// moving a file to the folder it is already in does nothing
+		if filepath.Dir(filepath.Clean(source)) == filepath.Clean(destination) {
+			continue
+		}
+
+		target, err := e.target(source, destination)
+		if err != nil {
+			return moved, err
+		}
+		if target == "" {
+			continue
+		}
+
+		err = e.move(ctx, source, target)
+		if err != nil {
+			return moved, err
+		}
+		moved = append(moved, Transfer{Source: source, Destination: target})
+	}
+
+	return moved, nil
+}
+
+// CopyTo copies source to target, which must not exist yet.
+func (e *Engine) CopyTo(ctx context.Context, source string, target string) error {
+	if err := e.start(KindCopy, []string{source}); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return &os.PathError{Op: "copy", Path: target, Err: os.ErrExist}
+	}
+	if isInside(target, source) {
+		return fmt.Errorf("%w: %s", ErrIntoItself, source)
+	}
+
+	return e.copy(ctx, source, target)
+}
+
+// MoveTo moves source to target, which must not exist yet.
+func (e *Engine) MoveTo(ctx context.Context, source string, target string) error {
+	if err := e.start(KindMove, []string{source}); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return &os.PathError{Op: "move", Path: target, Err: os.ErrExist}
+	}
+	if isInside(target, source) {
+		return fmt.Errorf("%w: %s", ErrIntoItself, source)
+	}
+
+	return e.move(ctx, source, target)
+}
+
+// Rename gives path a new name in the same folder and returns the new path.
+func (e *Engine) Rename(path string, name string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	target := filepath.Join(filepath.Dir(path), name)
+	if target == path {
+		return path, nil
+	}
+
+	// a case only rename on a case insensitive filesystem points at the same file
+	if info, err := os.Lstat(target); err == nil {
+		if sourceInfo, err := os.Lstat(path); err != nil || !os.SameFile(info, sourceInfo) {
+			return "", &os.PathError{Op: "rename", Path: target, Err: os.ErrExist}
+		}
+	}
+
+	e.progress = Progress{Kind: KindRename, Source: path, Destination: target, TotalFiles: 1}
+	if err := os.Rename(path, target); err != nil {
+		return "", err
+	}
+	e.advance(0, 1)
+
+	return target, nil
+}
+
+// Delete permanently removes paths.
+func (e *Engine) Delete(ctx context.Context, paths []string) error {
+	e.progress = Progress{Kind: KindDelete, TotalFiles: len(paths)}
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		e.progress.Source = path
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		e.advance(0, 1)
+	}
+
+	return nil
+}
+
+// CreateFolder creates a new folder called name inside dir. If the name is
+// taken a unique one is picked, the created path is returned.
+func (e *Engine) CreateFolder(dir string, name string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	target := UniqueName(filepath.Join(dir, name))
+	e.progress = Progress{Kind: KindCreateFolder, Destination: target, TotalFiles: 1}
+	if err := os.Mkdir(target, 0o755); err != nil {
+		return "", err
+	}
+	e.advance(0, 1)
+
+	return target, nil
+}
+
+// CreateFile creates a new empty file called name inside dir. If the name is
+// taken a unique one is picked, the created path is returned.
+func (e *Engine) CreateFile(dir string, name string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	target := UniqueName(filepath.Join(dir, name))
+	e.progress = Progress{Kind: KindCreateFile, Destination: target, TotalFiles: 1}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	e.advance(0, 1)
+
+	return target, nil
+}
+
+// UniqueName returns path if nothing exists there, otherwise the first free
+// "name (n).ext" next to it.
+func UniqueName(path string) string {
+	if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+		return path
+	}
+
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	// dotfiles such as .bashrc have no extension
+	if ext == base {
+		ext = ""
+	}
+	name := strings.TrimSuffix(base, ext)
+
+	for i := 1; ; i++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s (%d)%s", name, i, ext))
+		if _, err := os.Lstat(candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+	}
+}
+
+// start validates the sources and counts the work ahead for progress reports.
+func (e *Engine) start(kind Kind, sources []string) error {
+	e.progress = Progress{Kind: kind}
+
+	for _, source := range sources {
+		files, size, err := measure(source)
+		if err != nil {
+			return err
+		}
+		e.progress.TotalFiles += files
+		e.progress.TotalBytes += size
+	}
+
+	return nil
+}
+
+// target returns where source should go inside destination, after applying
+// the conflict policy. An empty path means the source should be skipped.
+func (e *Engine) target(source string, destination string) (string, error) {
+	source = filepath.Clean(source)
+	target := filepath.Join(destination, filepath.Base(source))
+
+	if target == source {
+		// copying onto itself always keeps both
+		return UniqueName(target), nil
+	}
+	if isInside(target, source) {
+		return "", fmt.Errorf("%w: %s", ErrIntoItself, source)
+	}
+
+	target, err := e.resolve(source, target)
+	if err != nil {
+		return "", err
+	}
+	// replacing a folder the source is in would delete the source with it
+	if target != "" && isInside(source, target) {
+		return "", fmt.Errorf("%w: %s", ErrOntoParent, source)
+	}
+	return target, nil
+}
+
+// resolve applies the conflict policy to target.
+func (e *Engine) resolve(source string, target string) (string, error) {
+	if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) {
+		return target, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	conflict := e.Conflict
+	if e.Resolve != nil {
+		conflict = e.Resolve(source, target)
+	}
+
+	switch conflict {
+	case Overwrite:
+		return target, nil
+	case KeepBoth:
+		return UniqueName(target), nil
+	default:
+		files, size, err := measure(source)
+		if err == nil {
+			e.advance(size, files)
+		}
+		return "", nil
+	}
+}
+
+func (e *Engine) move(ctx context.Context, source string, target string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+
+	// a file replaces a file in a single rename, and folders are merged.
+	// Anything else replaced is moved aside and only deleted once the source
+	// took its place, so a failed move loses nothing.
+	var aside string
+	if targetInfo, err := os.Lstat(target); err == nil && info.IsDir() != targetInfo.IsDir() {
+		aside = UniqueName(filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".replaced"))
+		if err := os.Rename(target, aside); err != nil {
+			return err
+		}
+	}
+
+	if err := e.rename(ctx, source, target); err != nil {
+		if aside != "" {
+			// put back what was replaced, over whatever part was moved
+			if os.RemoveAll(target) == nil {
+				os.Rename(aside, target)
+			}
+		}
+		return err
+	}
+
+	if aside != "" {
+		return os.RemoveAll(aside)
+	}
+	return nil
+}
+
+// rename moves source to target, which is either missing, a file to replace
+// or a folder to merge with.
+func (e *Engine) rename(ctx context.Context, source string, target string) error {
+	e.progress.Source = source
+	e.progress.Destination = target
+	err := os.Rename(source, target)
+	if err == nil {
+		files, size, _ := measure(target)
+		e.advance(size, files)
+		return nil
+	}
+
+	if !errors.Is(err, syscall.EXDEV) && !isDirNotEmpty(err) {
+		return err
+	}
+
+	// different filesystem or a folder merge: copy then remove the source
+	if err := e.copy(ctx, source, target); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(source)
+}
+
+// isInside returns true if path is dir or somewhere below it.
+func isInside(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func isDirNotEmpty(err error) bool {
+	return errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST)
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`) && !strings.ContainsRune(name, 0)
+}
+
+// advance adds work to the progress and reports it.
+func (e *Engine) advance(bytes int64, files int) {
+	e.progress.Bytes += bytes
+	e.progress.Files += files
+
+	if e.OnProgress != nil {
+		e.OnProgress(e.progress)
+	}
+}
@@ -0,0 +1,205 @@
+package fileops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// write creates a file with content, and the folders it is in.
+func write(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// read returns the content of a file, or "" if it doesn't exist.
+func read(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ""
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestTransferConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		move     bool
+		conflict Conflict
+		// the content of the destination and its sibling afterwards
+		destination string
+		kept        string
+		// whether the source is still there
+		source    bool
+		transfers int
+	}{
+		{"copy skip", false, Skip, "old", "", true, 0},
+		{"copy overwrite", false, Overwrite, "new", "", true, 1},
+		{"copy keep both", false, KeepBoth, "old", "new", true, 1},
+		{"move skip", true, Skip, "old", "", true, 0},
+		{"move overwrite", true, Overwrite, "new", "", false, 1},
+		{"move keep both", true, KeepBoth, "old", "new", false, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			source := filepath.Join(root, "source", "file.txt")
+			destination := filepath.Join(root, "destination")
+			write(t, source, "new")
+			write(t, filepath.Join(destination, "file.txt"), "old")
+
+			engine := NewEngine(test.conflict)
+			transfer := engine.Copy
+			if test.move {
+				transfer = engine.Move
+			}
+			transfers, err := transfer(context.Background(), []string{source}, destination)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(transfers) != test.transfers {
+				t.Fatalf("got %d transfers, want %d", len(transfers), test.transfers)
+			}
+			if got := read(t, filepath.Join(destination, "file.txt")); got != test.destination {
+				t.Errorf("destination has %q, want %q", got, test.destination)
+			}
+			if got := read(t, filepath.Join(destination, "file (1).txt")); got != test.kept {
+				t.Errorf("kept copy has %q, want %q", got, test.kept)
+			}
+			if got := read(t, source) != ""; got != test.source {
+				t.Errorf("source exists is %v, want %v", got, test.source)
+			}
+		})
+	}
+}
+
+func TestTransferOntoParent(t *testing.T) {
+	tests := []struct {
+		name     string
+		move     bool
+		conflict Conflict
+		// the error, and where the source went otherwise
+		err  error
+		kept string
+	}{
+		{"copy skip", false, Skip, nil, ""},
+		{"copy overwrite", false, Overwrite, ErrOntoParent, ""},
+		{"copy keep both", false, KeepBoth, nil, "x (1)"},
+		{"move skip", true, Skip, nil, ""},
+		{"move overwrite", true, Overwrite, ErrOntoParent, ""},
+		{"move keep both", true, KeepBoth, nil, "x (1)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			// replacing root/x with root/x/x would delete the source with it
+			source := filepath.Join(root, "x", "x")
+			write(t, filepath.Join(source, "file.txt"), "content")
+
+			engine := NewEngine(test.conflict)
+			transfer := engine.Copy
+			if test.move {
+				transfer = engine.Move
+			}
+			_, err := transfer(context.Background(), []string{source}, root)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if test.kept != "" {
+				if got := read(t, filepath.Join(root, test.kept, "file.txt")); got != "content" {
+					t.Errorf("%s has %q, want %q", test.kept, got, "content")
+				}
+			}
+			// only a move that went through takes the source away
+			want := "content"
+			if test.move && test.kept != "" {
+				want = ""
+			}
+			if got := read(t, filepath.Join(source, "file.txt")); got != want {
+				t.Errorf("the source has %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTransferIntoItself(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "x")
+	write(t, filepath.Join(source, "file.txt"), "content")
+
+	engine := NewEngine(Overwrite)
+	if _, err := engine.Copy(context.Background(), []string{source}, filepath.Join(source, "y")); !errors.Is(err, ErrIntoItself) {
+		t.Errorf("copy: got error %v, want %v", err, ErrIntoItself)
+	}
+	if _, err := engine.Move(context.Background(), []string{source}, source); !errors.Is(err, ErrIntoItself) {
+		t.Errorf("move: got error %v, want %v", err, ErrIntoItself)
+	}
+}
+
+func TestMoveReplacesOtherKind(t *testing.T) {
+	tests := []struct {
+		name string
+		// whether the source and the item it replaces are folders
+		source, target bool
+	}{
+		{"file onto file", false, false},
+		{"file onto folder", false, true},
+		{"folder onto file", true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			source := filepath.Join(root, "source", "x")
+			destination := filepath.Join(root, "destination")
+			target := filepath.Join(destination, "x")
+
+			if test.source {
+				write(t, filepath.Join(source, "file.txt"), "new")
+			} else {
+				write(t, source, "new")
+			}
+			if test.target {
+				write(t, filepath.Join(target, "file.txt"), "old")
+			} else {
+				write(t, target, "old")
+			}
+
+			if _, err := NewEngine(Overwrite).Move(context.Background(), []string{source}, destination); err != nil {
+				t.Fatal(err)
+			}
+
+			replaced := target
+			if test.source {
+				replaced = filepath.Join(target, "file.txt")
+			}
+			if got := read(t, replaced); got != "new" {
+				t.Errorf("the target has %q, want %q", got, "new")
+			}
+			// nothing set aside is left behind
+			entries, err := os.ReadDir(destination)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("got %d entries in the destination, want 1", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package explorer
+
+// History keeps track of the folders visited by the explorer so the user can
+// go back and forward between them.
+type History struct {
+	back    []string
+	current string
+	forward []string
+}
+
+// NewHistory creates a new history starting at path.
+func NewHistory(path string) *History {
+	return &History{
+		current: path,
+	}
+}
+
+// Current returns the path the explorer is currently at.
+func (h *History) Current() string {
+	return h.current
+}
+
+// Visit records a navigation to path, dropping the forward stack.
+func (h *History) Visit(path string) {
+	if path == h.current {
+		return
+	}
+
+	h.back = append(h.back, h.current)
+	h.current = path
+	h.forward = nil
+}
+
+// CanGoBack returns true if there is a previous folder to go back to.
+func (h *History) CanGoBack() bool {
+	return len(h.back) > 0
+}
+
+// CanGoForward returns true if there is a folder to go forward to.
+func (h *History) CanGoForward() bool {
+	return len(h.forward) > 0
+}
+
+// Back moves one step back and returns the path to show.
+func (h *History) Back() (string, bool) {
+	if !h.CanGoBack() {
+		return h.current, false
+	}
+
+	h.forward = append(h.forward, h.current)
+	h.current = h.back[len(h.back)-1]
+	h.back = h.back[:len(h.back)-1]
+
+	return h.current, true
+}
+
+// Forward moves one step forward and returns the path to show.
+func (h *History) Forward() (string, bool) {
+	if !h.CanGoForward() {
+		return h.current, false
+	}
+
+	h.back = append(h.back, h.current)
+	h.current = h.forward[len(h.forward)-1]
+	h.forward = h.forward[:len(h.forward)-1]
+
+	return h.current, true
+}
+
+// Items returns every path in the history, oldest first, including the
+// current one.
+func (h *History) Items() []string {
+	items := make([]string, 0, len(h.back)+1+len(h.forward))
+	items = append(items, h.back...)
+	items = append(items, h.current)
+	// the forward stack is stored with the nearest item last
+	for i := len(h.forward) - 1; i >= 0; i-- {
+		items = append(items, h.forward[i])
+	}
+
+	return items
+}
+
+// Index returns the position of the current path in Items.
+func (h *History) Index() int {
+	return len(h.back)
+}
+
+// Jump moves to the item at index in Items and returns its path.
+func (h *History) Jump(index int) (string, bool) {
+	items := h.Items()
+	if index < 0 || index >= len(items) {
+		return h.current, false
+	}
+
+	h.back = append([]string{}, items[:index]...)
+	h.current = items[index]
+	h.forward = h.forward[:0]
+	for i := len(items) - 1; i > index; i-- {
+		h.forward = append(h.forward, items[i])
+	}
+
+	return h.current, true
+}
@@ -0,0 +1,465 @@
+package index
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ShakedGold/Gole/pkg/explorer"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// settle is how long events are collected before the folders they
+	// happened in are read again
+	settle = 500 * time.Millisecond
+	// saveEvery is how often a changed index is stored
+	saveEvery = 5 * time.Minute
+)
+
+// Start loads the stored index and keeps it up to date in the background,
+// crawling the roots again if it was never built and reading the folders
+// changed since it was stored otherwise. It returns at once.
+func (ix *Index) Start() error {
+	if err := ix.load(); err != nil {
+		log.Println("index:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ix.cancel = cancel
+	ix.done = make(chan struct{})
+
+	ix.mu.RLock()
+	empty := len(ix.nodes) == ix.deleted
+	ix.mu.RUnlock()
+
+	go func() {
+		defer close(ix.done)
+		if empty {
+			ix.crawl(ctx)
+		} else {
+			ix.watchAll(ctx)
+			ix.rescan(ctx)
+		}
+		ix.listen(ctx)
+	}()
+	return nil
+}
+
+// Stop stops keeping the index up to date and stores it.
+func (ix *Index) Stop() error {
+	if ix.cancel == nil {
+		return nil
+	}
+	ix.cancel()
+	<-ix.done
+	ix.cancel = nil
+	ix.closeWatcher()
+
+	return ix.save()
+}
+
+// Pause stops crawling and following changes until Resume is called.
+func (ix *Index) Pause() {
+	ix.paused.Store(true)
+	ix.changed()
+}
+
+// Resume picks up where Pause left off. The index is built again if changes
+// were missed in between.
+func (ix *Index) Resume() {
+	ix.paused.Store(false)
+	if ix.missed.Swap(false) {
+		ix.Rebuild()
+	}
+	ix.changed()
+}
+
+// Rebuild throws the index away and crawls the roots again. It returns at
+// once, the crawl running so far is stopped in the background.
+func (ix *Index) Rebuild() {
+	if ix.cancel == nil {
+		return
+	}
+	ix.cancel()
+	stopped := ix.done
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ix.cancel = cancel
+	ix.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		<-stopped
+		ix.closeWatcher()
+		if ctx.Err() != nil {
+			return
+		}
+
+		ix.mu.Lock()
+		ix.reset()
+		ix.mu.Unlock()
+		ix.crawl(ctx)
+		ix.listen(ctx)
+	}(ix.done)
+}
+
+// crawl adds everything under the roots, watching every folder it finds.
+func (ix *Index) crawl(ctx context.Context) {
+	ix.crawling.Store(true)
+	ix.changed()
+	defer func() {
+		ix.crawling.Store(false)
+		ix.changed()
+	}()
+
+	if err := ix.watch(); err != nil {
+		log.Println("index:", err)
+		return
+	}
+
+	for _, root := range ix.Config.Roots {
+		info, err := os.Stat(root)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		ix.mu.Lock()
+		node := ix.add(-1, filepath.Clean(root), info)
+		ix.mu.Unlock()
+		if node < 0 {
+			return
+		}
+
+		if !ix.crawlFolder(ctx, node, filepath.Clean(root)) {
+			return
+		}
+	}
+
+	if err := ix.save(); err != nil {
+		log.Println("index:", err)
+	}
+}
+
+// crawlFolder adds everything under the folder at path, whose node is node.
+// It returns false if crawling has to stop.
+func (ix *Index) crawlFolder(ctx context.Context, node int32, path string) bool {
+	type folder struct {
+		node int32
+		path string
+	}
+	queue := []folder{{node, path}}
+
+	for len(queue) > 0 {
+		if !ix.wait(ctx) {
+			return false
+		}
+
+		current := queue[0]
+		queue = queue[1:]
+		ix.addWatch(current.path)
+
+		entries, err := os.ReadDir(current.path)
+		if err != nil {
+			continue
+		}
+
+		ix.mu.Lock()
+		for _, e := range entries {
+			if !ix.Config.Hidden && strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+
+			child := ix.add(current.node, e.Name(), info)
+			if child < 0 {
+				ix.mu.Unlock()
+				ix.changed()
+				return false
+			}
+			// symlinked folders are not followed, e.Info does not follow them
+			if info.IsDir() {
+				queue = append(queue, folder{child, filepath.Join(current.path, e.Name())})
+			}
+		}
+		ix.mu.Unlock()
+	}
+
+	return true
+}
+
+// wait returns once the index is not paused, false if it has to stop
+// instead.
+func (ix *Index) wait(ctx context.Context) bool {
+	for ix.paused.Load() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// watch creates the watcher following the changes under the roots.
+func (ix *Index) watch() error {
+	if ix.watcher != nil {
+		return nil
+	}
+	if len(ix.Config.Roots) == 0 {
+		return nil
+	}
+
+	watcher, err := explorer.Watcher(ix.Config.Roots[0])
+	if err != nil {
+		return err
+	}
+	ix.watcher = watcher
+	ix.watches = 1
+	return nil
+}
+
+// closeWatcher stops watching every folder.
+func (ix *Index) closeWatcher() {
+	if ix.watcher == nil {
+		return
+	}
+	ix.watcher.Close()
+	ix.watcher = nil
+	ix.watches = 0
+}
+
+// watchAll watches every folder of a loaded index.
+func (ix *Index) watchAll(ctx context.Context) {
+	if err := ix.watch(); err != nil {
+		log.Println("index:", err)
+		return
+	}
+
+	ix.mu.RLock()
+	paths := make([]string, 0, len(ix.dirs))
+	for path := range ix.dirs {
+		paths = append(paths, path)
+	}
+	ix.mu.RUnlock()
+
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return
+		}
+		ix.addWatch(path)
+	}
+}
+
+// rescan reads the folders of a loaded index again whose modification time
+// is not the one stored, as files were added or removed in them while the
+// index was not running.
+func (ix *Index) rescan(ctx context.Context) {
+	ix.crawling.Store(true)
+	ix.changed()
+	defer func() {
+		ix.crawling.Store(false)
+		ix.changed()
+	}()
+
+	type folder struct {
+		path     string
+		modified int64
+	}
+	ix.mu.RLock()
+	folders := make([]folder, 0, len(ix.dirs))
+	for path, node := range ix.dirs {
+		folders = append(folders, folder{path, ix.nodes[node].Modified})
+	}
+	ix.mu.RUnlock()
+
+	// the stale folders are all found first, reading a folder again updates
+	// the times of the folders in it
+	var stale []string
+	for _, f := range folders {
+		if ctx.Err() != nil {
+			return
+		}
+		info, err := os.Stat(f.path)
+		if err != nil || info.ModTime().UnixNano() != f.modified {
+			stale = append(stale, f.path)
+		}
+	}
+
+	for _, path := range stale {
+		if !ix.wait(ctx) {
+			return
+		}
+		ix.refresh(ctx, path)
+	}
+
+	ix.mu.Lock()
+	if ix.deleted > len(ix.nodes)/2 {
+		ix.compact()
+	}
+	ix.mu.Unlock()
+}
+
+// addWatch watches the folder at path, up to the number of watches allowed.
+func (ix *Index) addWatch(path string) {
+	if ix.watcher == nil || ix.watches >= ix.Config.MaxWatches {
+		return
+	}
+	if err := ix.watcher.Add(path); err != nil {
+		// most likely out of watches, the rest is only refreshed by
+		// rebuilding
+		ix.watches = ix.Config.MaxWatches
+		log.Println("index: not watching any more folders:", err)
+		return
+	}
+	ix.watches++
+}
+
+// listen applies the changes the watcher sees until ctx is done. Events are
+// collected for a moment and each folder they happened in is read once.
+func (ix *Index) listen(ctx context.Context) {
+	if ix.watcher == nil {
+		<-ctx.Done()
+		return
+	}
+
+	folders := map[string]bool{}
+	var timer <-chan time.Time
+	save := time.NewTicker(saveEvery)
+	defer save.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ix.watcher.Events:
+			if !ok {
+				return
+			}
+			if ix.paused.Load() {
+				ix.missed.Store(true)
+				continue
+			}
+
+			folders[filepath.Dir(event.Name)] = true
+			// a removed or renamed folder is gone with everything in it
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				folders[event.Name] = true
+			}
+			if timer == nil {
+				timer = time.After(settle)
+			}
+		case err, ok := <-ix.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Println("index:", err)
+		case <-timer:
+			timer = nil
+			for folder := range folders {
+				ix.refresh(ctx, folder)
+			}
+			folders = map[string]bool{}
+
+			// drop deleted nodes once they are most of the index
+			ix.mu.Lock()
+			if ix.deleted > len(ix.nodes)/2 {
+				ix.compact()
+			}
+			ix.mu.Unlock()
+			ix.changed()
+		case <-save.C:
+			if err := ix.save(); err != nil {
+				log.Println("index:", err)
+			}
+		}
+	}
+}
+
+// refresh reads the folder at path again and applies the differences.
+func (ix *Index) refresh(ctx context.Context, path string) {
+	ix.mu.RLock()
+	node, ok := ix.dirs[path]
+	ix.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	// the time is taken before reading, so a change in between is seen
+	// by the next rescan
+	info, err := os.Stat(path)
+	var entries []os.DirEntry
+	if err == nil {
+		entries, err = os.ReadDir(path)
+	}
+	if err != nil {
+		// the folder is gone
+		ix.mu.Lock()
+		ix.remove(node)
+		ix.mu.Unlock()
+		return
+	}
+
+	var added []int32
+	ix.mu.Lock()
+	ix.nodes[node].Modified = info.ModTime().UnixNano()
+	existing := map[string]int32{}
+	for _, child := range ix.children[node] {
+		if ix.nodes[child].Flags&flagDeleted == 0 {
+			existing[ix.name(child)] = child
+		}
+	}
+
+	for _, e := range entries {
+		if !ix.Config.Hidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		child, ok := existing[e.Name()]
+		delete(existing, e.Name())
+		if ok && (ix.nodes[child].Flags&flagDir != 0) == info.IsDir() {
+			ix.nodes[child].Bytes = info.Size()
+			ix.nodes[child].Modified = info.ModTime().UnixNano()
+			continue
+		}
+		if ok {
+			ix.remove(child)
+		}
+
+		if child = ix.add(node, e.Name(), info); child >= 0 && info.IsDir() {
+			added = append(added, child)
+		}
+	}
+
+	// what is left is not in the folder anymore
+	for _, child := range existing {
+		ix.remove(child)
+	}
+
+	ix.dirty = true
+	ix.mu.Unlock()
+
+	// new folders are crawled with what is in them
+	for _, child := range added {
+		ix.mu.RLock()
+		childPath := ix.path(child)
+		ix.mu.RUnlock()
+		ix.crawlFolder(ctx, child, childPath)
+	}
+}
+
+// changed tells the owner of the index that its status changed.
+func (ix *Index) changed() {
+	if ix.OnChange != nil {
+		ix.OnChange()
+	}
+}
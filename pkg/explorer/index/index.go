@@ -0,0 +1,470 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ShakedGold/Gole/pkg/explorer/search"
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrMemoryLimit is why an index is incomplete when it grew to its memory
+// limit.
+var ErrMemoryLimit = errors.New("the index reached its memory limit")
+
+const (
+	flagDir uint8 = 1 << iota
+	flagDeleted
+)
+
+// node is a file or folder of the index. Names are kept together in a single
+// buffer and paths are rebuilt from the parents, so millions of files fit in
+// little memory.
+type node struct {
+	// Offset is where the name is in the names buffer, Length its length
+	Offset uint32
+	Length uint16
+	// Parent is the node of the folder of the file, -1 for roots whose name
+	// is their full path
+	Parent int32
+	Bytes  int64
+	// Modified is the modification time in unix nanoseconds
+	Modified int64
+	Flags    uint8
+}
+
+// nodeSize is roughly what a node takes up in memory.
+const nodeSize = 40
+
+// dirSize is roughly what a folder takes up in the dirs map on top of its
+// node, and childrenSize what it takes up in the children map on top of its
+// list of children.
+const (
+	dirSize      = 128
+	childrenSize = 64
+)
+
+// largeBuffer is the size in bytes from which buffers grow by an eighth
+// instead of doubling, so the index never goes far over its memory limit.
+const largeBuffer = 1 << 20
+
+// Index is a list of every file under some roots that can be searched by
+// name in milliseconds. It is crawled in the background, kept up to date by
+// watching the folders, and stored on disk between runs.
+type Index struct {
+	Config Config
+	// Path is where the index is stored between runs, it is only kept in
+	// memory if empty
+	Path string
+	// OnChange, if set, is called when the status of the index changes
+	OnChange func()
+
+	mu    sync.RWMutex
+	nodes []node
+	// names holds the names of the nodes separated by zero bytes, lower the
+	// same names with ascii letters in lower case for searching
+	names []byte
+	lower []byte
+	// dirs finds the node of a folder from its path
+	dirs     map[string]int32
+	children map[int32][]int32
+	// links is the capacity of every list of children together
+	links   int64
+	deleted int
+	// full is set once the memory limit was reached
+	full bool
+	// dirty is set when the index changed since it was last saved
+	dirty bool
+
+	paused   atomic.Bool
+	crawling atomic.Bool
+	// missed is set when events were dropped while paused
+	missed atomic.Bool
+
+	watcher *fsnotify.Watcher
+	watches int
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Status tells how far the index is.
+type Status struct {
+	Files    int
+	Crawling bool
+	Paused   bool
+	// Err is why the index is incomplete, if it is
+	Err error
+}
+
+// New creates an empty index for config. It does nothing until started.
+func New(config Config) *Index {
+	ix := &Index{Config: config}
+	ix.reset()
+	return ix
+}
+
+// Status returns how far the index is.
+func (ix *Index) Status() Status {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	status := Status{
+		Files:    len(ix.nodes) - ix.deleted,
+		Crawling: ix.crawling.Load(),
+		Paused:   ix.paused.Load(),
+	}
+	if ix.full {
+		status.Err = ErrMemoryLimit
+	}
+	return status
+}
+
+// Covers returns true if path is under one of the roots of the index.
+func (ix *Index) Covers(path string) bool {
+	for _, root := range ix.Config.Roots {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk calls found with every file of the index under root that q matches,
+// like search.Walk does on the disk. Hidden files are only in the index if
+// its config says so, and .gitignore files are not looked at.
+func (ix *Index) Walk(ctx context.Context, root string, q search.Query, found func(path string, info fs.FileInfo) bool) error {
+	ix.mu.RLock()
+	matches := ix.query(root, q)
+	ix.mu.RUnlock()
+
+	for _, m := range matches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !found(m.path, m) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// query returns the nodes under root matching q, the caller holds the lock.
+func (ix *Index) query(root string, q search.Query) []fileInfo {
+	root = filepath.Clean(root)
+	prefix := root + string(filepath.Separator)
+	if root == string(filepath.Separator) {
+		prefix = root
+	}
+
+	rootNode, ok := ix.dirs[root]
+	if !ok {
+		rootNode = -1
+	}
+
+	var matches []fileInfo
+	check := func(i int32) {
+		n := ix.nodes[i]
+		if n.Flags&flagDeleted != 0 || n.Parent < 0 {
+			return
+		}
+
+		name := ix.name(i)
+		if !q.Hidden && strings.HasPrefix(name, ".") {
+			return
+		}
+		info := fileInfo{n: n, name: name}
+		if !q.Match(name, info) {
+			return
+		}
+		if !q.Hidden && ix.hiddenAbove(i, rootNode) {
+			return
+		}
+
+		info.path = ix.path(i)
+		if strings.HasPrefix(info.path, prefix) {
+			matches = append(matches, info)
+		}
+	}
+
+	// a plain word is looked up in the names directly, which is what makes
+	// the index fast
+	if word := plainWord(q); word != "" {
+		needle := asciiLower([]byte(word))
+		last := int32(-1)
+		for offset := 0; ; {
+			i := bytes.Index(ix.lower[offset:], needle)
+			if i < 0 {
+				break
+			}
+			offset += i
+
+			n := ix.nodeAt(offset)
+			if n != last {
+				check(n)
+				last = n
+			}
+			offset += len(needle)
+		}
+		return matches
+	}
+
+	for i := range ix.nodes {
+		check(int32(i))
+	}
+	return matches
+}
+
+// hiddenAbove returns true if a folder between the node root and the node i
+// is hidden.
+func (ix *Index) hiddenAbove(i int32, root int32) bool {
+	for p := ix.nodes[i].Parent; p >= 0 && p != root && ix.nodes[p].Parent >= 0; p = ix.nodes[p].Parent {
+		if strings.HasPrefix(ix.name(p), ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// plainWord returns the first name of q that is not a glob, or "".
+func plainWord(q search.Query) string {
+	for _, name := range q.Names {
+		if !strings.ContainsAny(name, "*?[") {
+			return name
+		}
+	}
+	return ""
+}
+
+// nodeAt returns the node whose name is at offset in the names buffer.
+func (ix *Index) nodeAt(offset int) int32 {
+	i := sort.Search(len(ix.nodes), func(i int) bool {
+		return int(ix.nodes[i].Offset) > offset
+	})
+	return int32(i - 1)
+}
+
+// name returns the name of the node i.
+func (ix *Index) name(i int32) string {
+	n := ix.nodes[i]
+	return string(ix.names[n.Offset : n.Offset+uint32(n.Length)])
+}
+
+// path returns the full path of the node i.
+func (ix *Index) path(i int32) string {
+	var parts []string
+	for ; i >= 0; i = ix.nodes[i].Parent {
+		parts = append(parts, ix.name(i))
+	}
+	for l, r := 0, len(parts)-1; l < r; l, r = l+1, r-1 {
+		parts[l], parts[r] = parts[r], parts[l]
+	}
+	return filepath.Join(parts...)
+}
+
+// add adds a node named name under parent and returns it, or -1 if the
+// memory limit was reached. The caller holds the lock.
+func (ix *Index) add(parent int32, name string, info fs.FileInfo) int32 {
+	// names longer than a node can hold are left out
+	if len(name) > 0xffff {
+		return -1
+	}
+
+	// everything that grows is checked against the limit before it does
+	var ok bool
+	if ix.names, ok = grow(ix, ix.names, len(name)+1, 1); !ok {
+		return ix.fill()
+	}
+	if ix.lower, ok = grow(ix, ix.lower, len(name)+1, 1); !ok {
+		return ix.fill()
+	}
+	if ix.nodes, ok = grow(ix, ix.nodes, 1, nodeSize); !ok {
+		return ix.fill()
+	}
+	if parent >= 0 {
+		siblings := ix.children[parent]
+		links := cap(siblings)
+		if siblings == nil && ix.usage()+childrenSize > ix.Config.MemoryLimit {
+			return ix.fill()
+		}
+		if siblings, ok = grow(ix, siblings, 1, 4); !ok {
+			return ix.fill()
+		}
+		ix.children[parent] = siblings
+		ix.links += int64(cap(siblings) - links)
+	}
+	if info.IsDir() && ix.usage()+dirSize > ix.Config.MemoryLimit {
+		return ix.fill()
+	}
+
+	n := node{
+		Offset:   uint32(len(ix.names)),
+		Length:   uint16(len(name)),
+		Parent:   parent,
+		Bytes:    info.Size(),
+		Modified: info.ModTime().UnixNano(),
+	}
+	if info.IsDir() {
+		n.Flags |= flagDir
+	}
+
+	start := len(ix.names)
+	ix.names = append(ix.names, name...)
+	ix.names = append(ix.names, 0)
+	ix.lower = append(ix.lower, ix.names[start:]...)
+	asciiLower(ix.lower[start:])
+
+	i := int32(len(ix.nodes))
+	ix.nodes = append(ix.nodes, n)
+	if parent >= 0 {
+		ix.children[parent] = append(ix.children[parent], i)
+	}
+	if info.IsDir() {
+		ix.dirs[ix.path(i)] = i
+	}
+	ix.dirty = true
+	return i
+}
+
+// remove drops the node i and everything under it. The caller holds the
+// lock.
+func (ix *Index) remove(i int32) {
+	if ix.nodes[i].Flags&flagDeleted != 0 {
+		return
+	}
+	for _, child := range ix.children[i] {
+		ix.remove(child)
+	}
+
+	if ix.nodes[i].Flags&flagDir != 0 {
+		delete(ix.dirs, ix.path(i))
+		ix.links -= int64(cap(ix.children[i]))
+		delete(ix.children, i)
+	}
+	ix.nodes[i].Flags |= flagDeleted
+	ix.deleted++
+	ix.dirty = true
+}
+
+// usage estimates the memory the index takes up.
+func (ix *Index) usage() int64 {
+	return int64(cap(ix.names)+cap(ix.lower)) +
+		int64(cap(ix.nodes))*nodeSize +
+		int64(len(ix.dirs))*dirSize +
+		int64(len(ix.children))*childrenSize + ix.links*4
+}
+
+// fill marks the index as having reached its memory limit and returns the
+// node add returns for it.
+func (ix *Index) fill() int32 {
+	ix.full = true
+	return -1
+}
+
+// grow returns s with room for n more elements of size bytes each. Small
+// buffers double, large ones grow by an eighth. It returns false, leaving s
+// as it is, if growing would take the index over its memory limit.
+func grow[E any](ix *Index, s []E, n int, size int64) ([]E, bool) {
+	if len(s)+n <= cap(s) {
+		return s, true
+	}
+
+	extra := max(cap(s), 8)
+	if int64(cap(s))*size > largeBuffer {
+		extra = cap(s) / 8
+	}
+	extra = max(extra, n)
+	if ix.usage()+int64(extra)*size > ix.Config.MemoryLimit {
+		return s, false
+	}
+
+	grown := make([]E, len(s), cap(s)+extra)
+	copy(grown, s)
+	return grown, true
+}
+
+// reset empties the index. The caller holds the lock.
+func (ix *Index) reset() {
+	ix.nodes = nil
+	ix.names = nil
+	ix.lower = nil
+	ix.dirs = map[string]int32{}
+	ix.children = map[int32][]int32{}
+	ix.links = 0
+	ix.deleted = 0
+	ix.full = false
+	ix.dirty = true
+}
+
+// compact drops the deleted nodes for good. The caller holds the lock.
+func (ix *Index) compact() {
+	if ix.deleted == 0 {
+		return
+	}
+
+	nodes, names, full := ix.nodes, ix.names, ix.full
+	ix.reset()
+	ix.addAll(nodes, names)
+	ix.full = ix.full || full
+}
+
+// addAll adds nodes with their names in names to an empty index, leaving out
+// the deleted ones and those past the memory limit. The caller holds the
+// lock.
+func (ix *Index) addAll(nodes []node, names []byte) {
+	// nodes always come after their parent, so parents are moved first
+	moved := make([]int32, len(nodes))
+	for i, n := range nodes {
+		moved[i] = -1
+		if n.Flags&flagDeleted != 0 {
+			continue
+		}
+		parent := n.Parent
+		if parent >= 0 {
+			if parent = moved[parent]; parent < 0 {
+				continue
+			}
+		}
+
+		name := string(names[n.Offset : n.Offset+uint32(n.Length)])
+		moved[i] = ix.add(parent, name, fileInfo{n: n, name: name})
+	}
+}
+
+// asciiLower puts the ascii letters of b in lower case, keeping its length
+// unlike strings.ToLower, and returns it.
+func asciiLower(b []byte) []byte {
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return b
+}
+
+// fileInfo is a node as a fs.FileInfo.
+type fileInfo struct {
+	n    node
+	name string
+	path string
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.n.Bytes }
+func (fi fileInfo) ModTime() time.Time { return time.Unix(0, fi.n.Modified) }
+func (fi fileInfo) IsDir() bool        { return fi.n.Flags&flagDir != 0 }
+func (fi fileInfo) Sys() any           { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.IsDir() {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
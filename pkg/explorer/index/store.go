@@ -0,0 +1,194 @@
+package index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// version changes whenever the stored index can't be read by older code.
+const version = 1
+
+// Config is what the index covers and how much it may take up.
+type Config struct {
+	// Enabled turns the index on, it is off by default
+	Enabled bool `json:"enabled"`
+	// Roots are the folders that are indexed with everything under them
+	Roots []string `json:"roots"`
+	// Hidden indexes hidden files and folders too
+	Hidden bool `json:"hidden"`
+	// MemoryLimit is roughly how many bytes the index may take up in memory,
+	// files found past it are left out
+	MemoryLimit int64 `json:"memoryLimit"`
+	// MaxWatches is how many folders are watched for changes at most
+	MaxWatches int `json:"maxWatches"`
+
+	path string
+}
+
+// NewConfig creates the default config, indexing the home folder when
+// enabled.
+func NewConfig() *Config {
+	config := &Config{
+		MemoryLimit: 256 << 20,
+		MaxWatches:  8192,
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		config.Roots = []string{home}
+	}
+	return config
+}
+
+// DefaultConfig loads the index config from the user's config folder.
+func DefaultConfig() (*Config, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadConfig(filepath.Join(configDir, "gole", "index.json"))
+}
+
+// LoadConfig loads the index config stored at path, a missing file gives the
+// default config.
+func LoadConfig(path string) (*Config, error) {
+	config := NewConfig()
+	config.path = path
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, err
+	}
+	for i, root := range config.Roots {
+		config.Roots[i] = filepath.Clean(root)
+	}
+	return config, nil
+}
+
+// Save writes the config where it was loaded from.
+func (c *Config) Save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, content, 0o600)
+}
+
+// DefaultPath returns where the index is stored in the user's cache folder.
+func DefaultPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "gole", "index.gob"), nil
+}
+
+// stored is the index as it is written to disk.
+type stored struct {
+	Version int
+	Roots   []string
+	Hidden  bool
+	Full    bool
+	Nodes   []node
+	Names   []byte
+}
+
+// load reads the index stored at Path. An index built for other roots is
+// ignored, and one larger than the memory limit is cut down to it.
+func (ix *Index) load() error {
+	if ix.Path == "" {
+		return nil
+	}
+
+	file, err := os.Open(ix.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var s stored
+	if err := gob.NewDecoder(file).Decode(&s); err != nil {
+		return err
+	}
+	if s.Version != version || s.Hidden != ix.Config.Hidden || !slices.Equal(s.Roots, ix.Config.Roots) {
+		return nil
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.reset()
+	ix.addAll(s.Nodes, s.Names)
+	// an index cut down has to be stored again
+	ix.dirty = len(ix.nodes) < len(s.Nodes)
+	ix.full = ix.full || s.Full
+	return nil
+}
+
+// save writes the index to Path if it changed since it was last saved,
+// next to its final location first so a crash never leaves a half written
+// file behind.
+func (ix *Index) save() error {
+	if ix.Path == "" {
+		return nil
+	}
+
+	ix.mu.Lock()
+	if !ix.dirty {
+		ix.mu.Unlock()
+		return nil
+	}
+	ix.compact()
+
+	var content bytes.Buffer
+	err := gob.NewEncoder(&content).Encode(stored{
+		Version: version,
+		Roots:   ix.Config.Roots,
+		Hidden:  ix.Config.Hidden,
+		Full:    ix.full,
+		Nodes:   ix.nodes,
+		Names:   ix.names,
+	})
+	if err == nil {
+		ix.dirty = false
+	}
+	ix.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ix.Path), 0o700); err != nil {
+		return err
+	}
+
+	temp := ix.Path + ".tmp"
+	if err := os.WriteFile(temp, content.Bytes(), 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, ix.Path)
+}
@@ -0,0 +1,276 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ShakedGold/Gole/pkg/explorer/fileops"
+)
+
+// Kind is the kind of filesystem mutation that was recorded.
+type Kind string
+
+const (
+	KindRename       Kind = "rename"
+	KindMove         Kind = "move"
+	KindCopy         Kind = "copy"
+	KindTrash        Kind = "trash"
+	KindRestore      Kind = "restore"
+	KindCreateFolder Kind = "create-folder"
+	KindCreateFile   Kind = "create-file"
+)
+
+// maxRecords is how many operations are kept on each stack.
+const maxRecords = 200
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrChanged is returned when the disk no longer looks like it did
+	// right after the operation, undoing it could lose data
+	ErrChanged = errors.New("the files changed since the operation")
+)
+
+// Change is a single path affected by an operation. From is empty for
+// created files, and the file in the trash for restored ones.
+type Change struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	// the state of To right after the operation
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"isDir"`
+}
+
+// Record is a single operation made by the explorer.
+type Record struct {
+	Kind    Kind      `json:"kind"`
+	Changes []Change  `json:"changes"`
+	Time    time.Time `json:"time"`
+}
+
+// Journal keeps the operations that can be undone and redone, and stores
+// them on disk so they survive a restart.
+type Journal struct {
+	Undone []Record `json:"undone"`
+	Done   []Record `json:"done"`
+
+	path   string
+	engine *fileops.Engine
+}
+
+// Default opens the journal in the user's state folder.
+func Default() (*Journal, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" || !filepath.IsAbs(stateHome) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		stateHome = filepath.Join(homeDir, ".local", "state")
+	}
+
+	return Open(filepath.Join(stateHome, "gole", "journal.json"))
+}
+
+// Open loads the journal stored at path, a missing file is an empty journal.
+func Open(path string) (*Journal, error) {
+	j := &Journal{
+		path:   path,
+		engine: fileops.NewEngine(fileops.Skip),
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, j); err != nil {
+		return nil, fmt.Errorf("reading journal %s: %w", path, err)
+	}
+
+	return j, nil
+}
+
+// CanUndo returns true if there is an operation to undo.
+func (j *Journal) CanUndo() bool {
+	return len(j.Done) > 0
+}
+
+// CanRedo returns true if there is an undone operation to redo.
+func (j *Journal) CanRedo() bool {
+	return len(j.Undone) > 0
+}
+
+// Record adds an operation that moved every path in from to the matching
+// path in to. Created files have no from paths.
+func (j *Journal) Record(kind Kind, from []string, to []string) error {
+	record := Record{
+		Kind: kind,
+		Time: time.Now(),
+	}
+
+	for i, target := range to {
+		change := Change{To: target}
+		if i < len(from) {
+			change.From = from[i]
+		}
+		if err := change.snapshot(); err != nil {
+			return err
+		}
+		record.Changes = append(record.Changes, change)
+	}
+	if len(record.Changes) == 0 {
+		return nil
+	}
+
+	j.Done = push(j.Done, record)
+	j.Undone = nil
+
+	return j.save()
+}
+
+// RecordTransfers adds a copy or move made by the file operations engine.
+func (j *Journal) RecordTransfers(kind Kind, transfers []fileops.Transfer) error {
+	from := make([]string, len(transfers))
+	to := make([]string, len(transfers))
+	for i, transfer := range transfers {
+		from[i] = transfer.Source
+		to[i] = transfer.Destination
+	}
+
+	return j.Record(kind, from, to)
+}
+
+// Undo reverts the last operation and returns it.
+func (j *Journal) Undo() (Record, error) {
+	if !j.CanUndo() {
+		return Record{}, ErrNothingToUndo
+	}
+	record := j.Done[len(j.Done)-1]
+
+	reverted, err := j.undo(&record)
+	if err != nil {
+		if reverted == 0 {
+			return Record{}, err
+		}
+		// the reverted changes can be redone, the rest stays to be undone
+		done, undone := record.split(len(record.Changes) - reverted)
+		j.Done[len(j.Done)-1] = done
+		j.Undone = push(j.Undone, undone)
+		return Record{}, errors.Join(err, j.save())
+	}
+
+	j.Done = j.Done[:len(j.Done)-1]
+	j.Undone = push(j.Undone, record)
+
+	return record, j.save()
+}
+
+// Redo applies the last undone operation again and returns it.
+func (j *Journal) Redo() (Record, error) {
+	if !j.CanRedo() {
+		return Record{}, ErrNothingToRedo
+	}
+	record := j.Undone[len(j.Undone)-1]
+
+	applied, err := j.redo(&record)
+	if err != nil {
+		if applied == 0 {
+			return Record{}, err
+		}
+		// the applied changes can be undone, the rest stays to be redone
+		done, undone := record.split(applied)
+		j.Undone = j.Undone[:len(j.Undone)-1]
+		if len(undone.Changes) > 0 {
+			j.Undone = append(j.Undone, undone)
+		}
+		j.Done = push(j.Done, done)
+		return Record{}, errors.Join(err, j.save())
+	}
+
+	j.Undone = j.Undone[:len(j.Undone)-1]
+	j.Done = push(j.Done, record)
+
+	return record, j.save()
+}
+
+// save writes the journal next to its final location first so a crash never
+// leaves a half written file behind.
+func (j *Journal) save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return err
+	}
+
+	temp := j.path + ".tmp"
+	if err := os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, j.path)
+}
+
+// split returns a record with the changes before i and one with the rest.
+func (r Record) split(i int) (Record, Record) {
+	before, after := r, r
+	before.Changes = append([]Change(nil), r.Changes[:i]...)
+	after.Changes = append([]Change(nil), r.Changes[i:]...)
+	return before, after
+}
+
+// snapshot records the current state of To.
+func (c *Change) snapshot() error {
+	info, err := os.Lstat(c.To)
+	if err != nil {
+		return err
+	}
+
+	c.ModTime = info.ModTime()
+	c.Size = info.Size()
+	c.IsDir = info.IsDir()
+	if c.IsDir {
+		// the size of a folder entry means nothing across filesystems
+		c.Size = 0
+	}
+
+	return nil
+}
+
+// unchanged returns an error if To doesn't look like it did when the
+// change was recorded.
+func (c Change) unchanged() error {
+	info, err := os.Lstat(c.To)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrChanged, err)
+	}
+
+	if info.IsDir() != c.IsDir || !info.ModTime().Equal(c.ModTime) || (!c.IsDir && info.Size() != c.Size) {
+		return fmt.Errorf("%w: %s was modified", ErrChanged, c.To)
+	}
+
+	return nil
+}
+
+func push(records []Record, record Record) []Record {
+	records = append(records, record)
+	if len(records) > maxRecords {
+		records = records[len(records)-maxRecords:]
+	}
+	return records
+}
@@ -0,0 +1,203 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ShakedGold/Gole/pkg/explorer/fileops"
+	"github.com/ShakedGold/Gole/pkg/explorer/trash"
+)
+
+// open returns an empty journal stored in a temporary folder.
+func open(t *testing.T) *Journal {
+	t.Helper()
+
+	j, err := Open(filepath.Join(t.TempDir(), "journal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+// create makes empty files at paths, and the folders they are in.
+func create(t *testing.T, paths ...string) {
+	t.Helper()
+
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// exist fails the test unless every path exists, or none does.
+func exist(t *testing.T, want bool, paths ...string) {
+	t.Helper()
+
+	for _, path := range paths {
+		_, err := os.Lstat(path)
+		if got := err == nil; got != want {
+			t.Errorf("%s exists is %v, want %v", path, got, want)
+		}
+	}
+}
+
+// move moves sources into destination and records it.
+func move(t *testing.T, j *Journal, sources []string, destination string) []fileops.Transfer {
+	t.Helper()
+
+	moved, err := fileops.NewEngine(fileops.Skip).Move(context.Background(), sources, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.RecordTransfers(KindMove, moved); err != nil {
+		t.Fatal(err)
+	}
+	return moved
+}
+
+func TestUndoRefusedWhenChanged(t *testing.T) {
+	root := t.TempDir()
+	j := open(t)
+
+	file := filepath.Join(root, "file.txt")
+	create(t, file)
+	if err := j.Record(KindCreateFile, nil, []string{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file was written to after it was created
+	if err := os.WriteFile(file, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.Undo(); !errors.Is(err, ErrChanged) {
+		t.Fatalf("got error %v, want %v", err, ErrChanged)
+	}
+	exist(t, true, file)
+	if !j.CanUndo() || j.CanRedo() {
+		t.Fatal("a refused undo changed the journal")
+	}
+}
+
+func TestMoveRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	j := open(t)
+
+	sources := []string{
+		filepath.Join(root, "source", "a.txt"),
+		filepath.Join(root, "source", "b.txt"),
+		filepath.Join(root, "source", "folder", "c.txt"),
+	}
+	create(t, sources...)
+	sources[2] = filepath.Dir(sources[2])
+	destination := filepath.Join(root, "destination")
+	if err := os.Mkdir(destination, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	moved := move(t, j, sources, destination)
+	var targets []string
+	for _, transfer := range moved {
+		targets = append(targets, transfer.Destination)
+	}
+
+	for range 2 {
+		if _, err := j.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		exist(t, true, sources...)
+		exist(t, false, targets...)
+
+		if _, err := j.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		exist(t, false, sources...)
+		exist(t, true, targets...)
+	}
+	exist(t, true, filepath.Join(destination, "folder", "c.txt"))
+}
+
+func TestRestoreRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	// the home trash is on the same filesystem as the file
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "data"))
+	j := open(t)
+
+	file := filepath.Join(root, "file.txt")
+	create(t, file)
+	item, err := trash.Put(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trash.Restore(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record(KindRestore, []string{item.File()}, []string{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := j.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		exist(t, false, file)
+		if _, ok := trash.Lookup(j.Undone[0].Changes[0].From); !ok {
+			t.Fatal("the file undone is not in the trash")
+		}
+
+		if _, err := j.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		exist(t, true, file)
+	}
+}
+
+func TestUndoSplitsOnFailure(t *testing.T) {
+	root := t.TempDir()
+	j := open(t)
+
+	first := filepath.Join(root, "first", "a.txt")
+	second := filepath.Join(root, "second", "b.txt")
+	create(t, first, second)
+	destination := filepath.Join(root, "destination")
+	if err := os.Mkdir(destination, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	move(t, j, []string{first, second}, destination)
+
+	// the last change is undone first and succeeds, the first one has
+	// nowhere to go
+	if err := os.Remove(filepath.Dir(first)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(); err == nil {
+		t.Fatal("undo into a missing folder succeeded")
+	}
+	exist(t, true, second)
+
+	if len(j.Done) != 1 || len(j.Done[0].Changes) != 1 || j.Done[0].Changes[0].From != first {
+		t.Fatalf("the change not undone was not kept, done is %+v", j.Done)
+	}
+	if len(j.Undone) != 1 || len(j.Undone[0].Changes) != 1 || j.Undone[0].Changes[0].From != second {
+		t.Fatalf("the undone change was not split off, undone is %+v", j.Undone)
+	}
+
+	// both parts can be finished on their own
+	if err := os.Mkdir(filepath.Dir(first), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	exist(t, true, first, second)
+	if len(j.Undone) != 2 {
+		t.Fatalf("got %d undone records, want 2", len(j.Undone))
+	}
+}
@@ -0,0 +1,135 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ShakedGold/Gole/pkg/explorer/trash"
+)
+
+// undo reverts record, last change first, and returns how many of the
+// changes at its end were reverted. Every change is checked before anything
+// is touched so a refused undo leaves the disk as it was.
+func (j *Journal) undo(record *Record) (int, error) {
+	for _, change := range record.Changes {
+		if err := change.unchanged(); err != nil {
+			return 0, err
+		}
+		// a file goes back to the trash under whatever name is free there
+		if change.From != "" && record.Kind != KindCopy && record.Kind != KindRestore {
+			if err := free(change.From); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for i := len(record.Changes) - 1; i >= 0; i-- {
+		change := &record.Changes[i]
+
+		var err error
+		switch record.Kind {
+		case KindRename, KindMove:
+			err = j.engine.MoveTo(context.Background(), change.To, change.From)
+		case KindCopy:
+			// the copy goes to the trash so undoing is never destructive
+			_, err = trash.Put(change.To)
+		case KindTrash:
+			item, ok := trash.Lookup(change.To)
+			if !ok {
+				return len(record.Changes) - 1 - i, fmt.Errorf("%w: %s is no longer in the trash", ErrChanged, change.To)
+			}
+			_, err = trash.Restore(item)
+		case KindRestore:
+			var item trash.Item
+			item, err = trash.Put(change.To)
+			change.From = item.File()
+		case KindCreateFolder:
+			// fails if anything was put in the folder since
+			err = os.Remove(change.To)
+		case KindCreateFile:
+			err = os.Remove(change.To)
+		default:
+			err = fmt.Errorf("unknown operation %q", record.Kind)
+		}
+		if err != nil {
+			return len(record.Changes) - 1 - i, err
+		}
+	}
+
+	return len(record.Changes), nil
+}
+
+// redo applies record again, first change first, and updates the recorded
+// state of its changes. It returns how many of the changes at its start
+// were applied.
+func (j *Journal) redo(record *Record) (int, error) {
+	for _, change := range record.Changes {
+		if change.From != "" {
+			if _, err := os.Lstat(change.From); err != nil {
+				return 0, fmt.Errorf("%w: %v", ErrChanged, err)
+			}
+		}
+		if record.Kind != KindTrash {
+			if err := free(change.To); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for i := range record.Changes {
+		change := &record.Changes[i]
+
+		var err error
+		switch record.Kind {
+		case KindRename, KindMove:
+			err = j.engine.MoveTo(context.Background(), change.From, change.To)
+		case KindCopy:
+			err = j.engine.CopyTo(context.Background(), change.From, change.To)
+		case KindTrash:
+			var item trash.Item
+			item, err = trash.Put(change.From)
+			change.To = item.File()
+		case KindRestore:
+			item, ok := trash.Lookup(change.From)
+			if !ok {
+				return i, fmt.Errorf("%w: %s is no longer in the trash", ErrChanged, change.From)
+			}
+			_, err = trash.Restore(item)
+		case KindCreateFolder:
+			err = os.Mkdir(change.To, 0o755)
+		case KindCreateFile:
+			var file *os.File
+			file, err = os.OpenFile(change.To, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+			if err == nil {
+				err = file.Close()
+			}
+		default:
+			err = fmt.Errorf("unknown operation %q", record.Kind)
+		}
+		if err != nil {
+			return i, err
+		}
+
+		// the change was made even if its state can't be read, undoing it
+		// will be refused rather than repeating it
+		if err := change.snapshot(); err != nil {
+			return i + 1, err
+		}
+	}
+
+	return len(record.Changes), nil
+}
+
+// free returns an error if something exists at path.
+func free(path string) error {
+	_, err := os.Lstat(path)
+	if err == nil {
+		return fmt.Errorf("%w: %s already exists", ErrChanged, path)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
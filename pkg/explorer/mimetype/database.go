@@ -0,0 +1,348 @@
+package mimetype
+
+import (
+	"bufio"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// db is the shared-mime-info database of the freedesktop.org specification,
+// as installed by most Linux desktops. It is read once, the first time a type
+// is detected.
+type db struct {
+	// literals are the names with a type, extensions the extensions, both
+	// in lower case. The case sensitive ones are apart, as they are.
+	literals        map[string]glob
+	extensions      map[string]glob
+	exactLiterals   map[string]glob
+	exactExtensions map[string]glob
+	// globs are the other patterns, by weight
+	globs []glob
+	// magic are the rules recognizing types from the content, by priority
+	magic   []magic
+	parents map[string]string
+	// icons and genericIcons are the names of the icons of the types that
+	// have one in the icon themes
+	icons        map[string]string
+	genericIcons map[string]string
+	// dirs are the mime folders the database was read from
+	dirs []string
+}
+
+// glob is a pattern of names of files of a type.
+type glob struct {
+	weight        int
+	mime          string
+	pattern       string
+	caseSensitive bool
+}
+
+var (
+	loadOnce sync.Once
+	loaded   *db
+
+	commentsMu sync.Mutex
+	comments   = map[string]string{}
+)
+
+// database returns the shared-mime-info database, or nil if none is
+// installed.
+func database() *db {
+	loadOnce.Do(func() {
+		loaded = load(dataDirs())
+	})
+	return loaded
+}
+
+// dataDirs returns the mime folders of the XDG data folders, the most
+// important first.
+func dataDirs() []string {
+	home := os.Getenv("XDG_DATA_HOME")
+	if home == "" {
+		if dir, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(dir, ".local", "share")
+		}
+	}
+	dirs := os.Getenv("XDG_DATA_DIRS")
+	if dirs == "" {
+		dirs = "/usr/local/share:/usr/share"
+	}
+
+	var mimeDirs []string
+	for _, dir := range append([]string{home}, filepath.SplitList(dirs)...) {
+		if dir != "" {
+			mimeDirs = append(mimeDirs, filepath.Join(dir, "mime"))
+		}
+	}
+	return mimeDirs
+}
+
+// load reads the database in dirs, nil if there is none.
+func load(dirs []string) *db {
+	d := &db{
+		literals:        map[string]glob{},
+		extensions:      map[string]glob{},
+		exactLiterals:   map[string]glob{},
+		exactExtensions: map[string]glob{},
+		parents:         map[string]string{},
+		icons:           map[string]string{},
+		genericIcons:    map[string]string{},
+	}
+
+	// the first folders override the later ones, so they are read last
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if _, err := os.Stat(filepath.Join(dirs[i], "globs2")); err != nil {
+			continue
+		}
+		d.dirs = append([]string{dirs[i]}, d.dirs...)
+		d.readGlobs(filepath.Join(dirs[i], "globs2"))
+		d.readParents(filepath.Join(dirs[i], "subclasses"))
+		readPairs(filepath.Join(dirs[i], "icons"), d.icons)
+		readPairs(filepath.Join(dirs[i], "generic-icons"), d.genericIcons)
+		if rules, err := readMagic(filepath.Join(dirs[i], "magic")); err == nil {
+			d.magic = append(d.magic, rules...)
+		}
+	}
+	if len(d.dirs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(d.globs, func(i, j int) bool {
+		return d.globs[i].weight > d.globs[j].weight
+	})
+	sort.SliceStable(d.magic, func(i, j int) bool {
+		return d.magic[i].priority > d.magic[j].priority
+	})
+	return d
+}
+
+// readGlobs reads a globs2 file, made of weight:type:pattern[:flags] lines.
+func (d *db) readGlobs(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 4)
+		if len(fields) < 3 {
+			continue
+		}
+		weight, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		g := glob{weight: weight, mime: fields[1], pattern: fields[2]}
+		if len(fields) == 4 {
+			g.caseSensitive = strings.Contains(fields[3], "cs")
+		}
+		if !g.caseSensitive {
+			g.pattern = strings.ToLower(g.pattern)
+		}
+
+		literals, extensions := d.literals, d.extensions
+		if g.caseSensitive {
+			literals, extensions = d.exactLiterals, d.exactExtensions
+		}
+		switch {
+		case !strings.ContainsAny(g.pattern, "*?["):
+			literals[g.pattern] = better(literals[g.pattern], g)
+		case strings.HasPrefix(g.pattern, "*.") && !strings.ContainsAny(g.pattern[2:], "*?["):
+			extensions[g.pattern[2:]] = better(extensions[g.pattern[2:]], g)
+		default:
+			d.globs = append(d.globs, g)
+		}
+	}
+}
+
+// better returns the glob of the two that wins when both match.
+func better(a, b glob) glob {
+	if a.mime == "" || b.weight > a.weight {
+		return b
+	}
+	return a
+}
+
+// readParents reads a subclasses file, made of "type parent" lines.
+func (d *db) readParents(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		mime, parent, found := strings.Cut(scanner.Text(), " ")
+		// the first parent is kept, text/plain comes last for scripts
+		if found && d.parents[mime] == "" {
+			d.parents[mime] = parent
+		}
+	}
+}
+
+// readPairs reads a file of "type:value" lines into values.
+func readPairs(path string, values map[string]string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if mime, value, found := strings.Cut(scanner.Text(), ":"); found {
+			values[mime] = value
+		}
+	}
+}
+
+// glob returns the type of a file named name, or "". Literal names come
+// first, then the longest extension, then the other patterns.
+func (d *db) glob(name string) string {
+	lower := strings.ToLower(name)
+	if g, ok := d.exactLiterals[name]; ok {
+		return g.mime
+	}
+	if g, ok := d.literals[lower]; ok {
+		return g.mime
+	}
+
+	// the longest extension wins, .tar.gz over .gz, and a case sensitive
+	// one over the others as long, .C over .c
+	exact, exactLength := extension(d.exactExtensions, name)
+	g, length := extension(d.extensions, lower)
+	if exactLength > 0 && exactLength >= length {
+		return exact.mime
+	}
+	if length > 0 {
+		return g.mime
+	}
+
+	for _, g := range d.globs {
+		candidate := lower
+		if g.caseSensitive {
+			candidate = name
+		}
+		if matched, _ := filepath.Match(g.pattern, candidate); matched {
+			return g.mime
+		}
+	}
+	return ""
+}
+
+// extension returns the glob of the longest extension of name found in
+// extensions and the length of that extension, 0 if none is.
+func extension(extensions map[string]glob, name string) (glob, int) {
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if g, ok := extensions[name[i+1:]]; ok {
+			return g, len(name) - i - 1
+		}
+	}
+	return glob{}, 0
+}
+
+// match returns the type the magic rules recognize in head, or "".
+func (d *db) match(head []byte) string {
+	for _, m := range d.magic {
+		if m.matches(head) {
+			return m.mime
+		}
+	}
+	return ""
+}
+
+// comment returns the description of the type mime in its definition, or
+// one made up from its name.
+func comment(mime string) string {
+	commentsMu.Lock()
+	defer commentsMu.Unlock()
+
+	if c, ok := comments[mime]; ok {
+		return c
+	}
+
+	c := ""
+	if d := database(); d != nil {
+		for _, dir := range d.dirs {
+			if c = readComment(filepath.Join(dir, mime+".xml")); c != "" {
+				break
+			}
+		}
+	}
+	if c == "" {
+		c = madeUpComment(mime)
+	}
+	// the database comments start in lower case, like "plain text document"
+	if r, size := utf8.DecodeRuneInString(c); r != utf8.RuneError {
+		c = string(unicode.ToUpper(r)) + c[size:]
+	}
+	comments[mime] = c
+	return c
+}
+
+// readComment returns the comment in english of the type defined in the file
+// at path.
+func readComment(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	var definition struct {
+		Comments []struct {
+			Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"comment"`
+	}
+	if err := xml.Unmarshal(content, &definition); err != nil {
+		return ""
+	}
+	for _, c := range definition.Comments {
+		if c.Lang == "" {
+			return c.Text
+		}
+	}
+	return ""
+}
+
+// madeUpComment describes mime without a database, like "PNG image".
+func madeUpComment(mime string) string {
+	switch mime {
+	case Directory:
+		return "Folder"
+	case Symlink:
+		return "Link"
+	case Empty:
+		return "Empty document"
+	case Binary:
+		return "Binary"
+	case Text:
+		return "Plain text document"
+	}
+
+	_, subtype, _ := strings.Cut(mime, "/")
+	subtype = strings.TrimPrefix(subtype, "x-")
+	subtype, _, _ = strings.Cut(subtype, "+")
+	if i := strings.LastIndexByte(subtype, '.'); i >= 0 {
+		subtype = subtype[i+1:]
+	}
+	return strings.ToUpper(subtype) + " " + strings.ToLower(kindOf(mime).Title())
+}
@@ -0,0 +1,161 @@
+package mimetype
+
+import "strings"
+
+// Kind is a broad family of types, each with its own icon.
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindFolder
+	KindSymlink
+	KindText
+	KindDocument
+	KindCode
+	KindImage
+	KindAudio
+	KindVideo
+	KindArchive
+	KindExecutable
+	// KindBinary is data with no known type
+	KindBinary
+)
+
+// Title returns the name of the kind as shown to the user.
+func (k Kind) Title() string {
+	switch k {
+	case KindFolder:
+		return "Folder"
+	case KindSymlink:
+		return "Link"
+	case KindText:
+		return "Text"
+	case KindDocument:
+		return "Document"
+	case KindCode:
+		return "Source code"
+	case KindImage:
+		return "Image"
+	case KindAudio:
+		return "Audio"
+	case KindVideo:
+		return "Video"
+	case KindArchive:
+		return "Archive"
+	case KindExecutable:
+		return "Program"
+	case KindBinary:
+		return "Binary"
+	default:
+		return "File"
+	}
+}
+
+// kinds are the kinds of the types that don't tell it by their name.
+var kinds = map[string]Kind{
+	Directory: KindFolder,
+	Symlink:   KindSymlink,
+	Binary:    KindBinary,
+	Empty:     KindText,
+	Text:      KindText,
+
+	"text/markdown":                 KindDocument,
+	"text/csv":                      KindDocument,
+	"application/pdf":               KindDocument,
+	"application/rtf":               KindDocument,
+	"application/msword":            KindDocument,
+	"application/vnd.ms-excel":      KindDocument,
+	"application/vnd.ms-powerpoint": KindDocument,
+	"application/epub+zip":          KindDocument,
+
+	"text/html":                 KindCode,
+	"text/css":                  KindCode,
+	"text/rust":                 KindCode,
+	"application/javascript":    KindCode,
+	"application/json":          KindCode,
+	"application/xml":           KindCode,
+	"application/sql":           KindCode,
+	"application/toml":          KindCode,
+	"application/x-shellscript": KindCode,
+	"application/x-yaml":        KindCode,
+	"application/x-perl":        KindCode,
+	"application/x-php":         KindCode,
+	"application/x-ruby":        KindCode,
+	"application/x-typescript":  KindCode,
+
+	"application/zip":                       KindArchive,
+	"application/gzip":                      KindArchive,
+	"application/zstd":                      KindArchive,
+	"application/vnd.rar":                   KindArchive,
+	"application/vnd.debian.binary-package": KindArchive,
+	"application/java-archive":              KindArchive,
+	"application/x-tar":                     KindArchive,
+	"application/x-compressed-tar":          KindArchive,
+	"application/x-bzip2":                   KindArchive,
+	"application/x-xz":                      KindArchive,
+	"application/x-7z-compressed":           KindArchive,
+	"application/x-rar":                     KindArchive,
+	"application/x-rpm":                     KindArchive,
+	"application/x-cd-image":                KindArchive,
+	"application/x-iso9660-image":           KindArchive,
+
+	"application/x-executable":                      KindExecutable,
+	"application/x-pie-executable":                  KindExecutable,
+	"application/x-sharedlib":                       KindExecutable,
+	"application/x-msdownload":                      KindExecutable,
+	"application/x-ms-dos-executable":               KindExecutable,
+	"application/vnd.microsoft.portable-executable": KindExecutable,
+	"application/x-mach-binary":                     KindExecutable,
+	"application/vnd.appimage":                      KindExecutable,
+}
+
+// kindOf returns the kind of files of type mime. Types not known themselves
+// take the kind of the types they are a special case of.
+func kindOf(mime string) Kind {
+	for seen := 0; mime != "" && seen < 8; seen++ {
+		if kind, ok := kinds[mime]; ok {
+			return kind
+		}
+		if kind := kindOfFamily(mime); kind != KindUnknown {
+			return kind
+		}
+		mime = parent(mime)
+	}
+	return KindUnknown
+}
+
+// kindOfFamily tells the kind of a type from the family it belongs to.
+func kindOfFamily(mime string) Kind {
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return KindImage
+	case strings.HasPrefix(mime, "audio/"):
+		return KindAudio
+	case strings.HasPrefix(mime, "video/"):
+		return KindVideo
+	case strings.HasPrefix(mime, "text/x-"):
+		return KindCode
+	case strings.HasPrefix(mime, "application/vnd.oasis.opendocument."),
+		strings.HasPrefix(mime, "application/vnd.openxmlformats-officedocument."):
+		return KindDocument
+	}
+	return KindUnknown
+}
+
+// parent returns the type mime is a special case of, or "".
+func parent(mime string) string {
+	if db := database(); db != nil {
+		if parent := db.parents[mime]; parent != "" {
+			return parent
+		}
+	}
+	switch {
+	case strings.HasPrefix(mime, "text/"):
+		return Text
+	case strings.HasSuffix(mime, "+xml"):
+		return "application/xml"
+	case strings.HasSuffix(mime, "+json"):
+		return "application/json"
+	}
+	return ""
+}
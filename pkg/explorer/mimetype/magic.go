@@ -0,0 +1,253 @@
+package mimetype
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// magicHeader starts every magic file of the database.
+const magicHeader = "MIME-Magic\x00\n"
+
+var errMagic = errors.New("invalid magic file")
+
+// magic are the rules recognizing a type from the start of the content.
+type magic struct {
+	priority int
+	mime     string
+	rules    []*rule
+}
+
+// rule matches value at an offset of the content, and one of its children if
+// it has any.
+type rule struct {
+	indent   int
+	offset   int
+	value    []byte
+	mask     []byte
+	rangeLen int
+	children []*rule
+}
+
+// matches tells if one of the rules matches head.
+func (m magic) matches(head []byte) bool {
+	for _, r := range m.rules {
+		if r.matches(head) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) matches(head []byte) bool {
+	if !r.matchesValue(head) {
+		return false
+	}
+	if len(r.children) == 0 {
+		return true
+	}
+	for _, child := range r.children {
+		if child.matches(head) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesValue tells if the value is found at one of the offsets of the
+// range.
+func (r *rule) matchesValue(head []byte) bool {
+	for start := r.offset; start < r.offset+r.rangeLen; start++ {
+		if start+len(r.value) > len(head) {
+			return false
+		}
+		if r.mask == nil {
+			if bytes.Equal(head[start:start+len(r.value)], r.value) {
+				return true
+			}
+			continue
+		}
+
+		matched := true
+		for i, b := range r.value {
+			if head[start+i]&r.mask[i] != b&r.mask[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readMagic reads the magic file at path.
+func readMagic(path string) ([]magic, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header := make([]byte, len(magicHeader))
+	if _, err := io.ReadFull(reader, header); err != nil || string(header) != magicHeader {
+		return nil, errMagic
+	}
+
+	var sections []magic
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return sections, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if b == '[' {
+			section, err := readSection(reader)
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, section)
+			continue
+		}
+		if len(sections) == 0 {
+			return nil, errMagic
+		}
+
+		reader.UnreadByte()
+		r, err := readRule(reader)
+		if err != nil {
+			return nil, err
+		}
+		sections[len(sections)-1].add(r)
+	}
+}
+
+// readSection reads the "priority:type]\n" heading a section.
+func readSection(reader *bufio.Reader) (magic, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return magic{}, errMagic
+	}
+	priority, mime, found := strings.Cut(strings.TrimSuffix(line, "]\n"), ":")
+	if !found {
+		return magic{}, errMagic
+	}
+	p, err := strconv.Atoi(priority)
+	if err != nil {
+		return magic{}, errMagic
+	}
+	return magic{priority: p, mime: mime}, nil
+}
+
+// add places r in the tree of rules, under the last rule indented less.
+func (m *magic) add(r *rule) {
+	siblings := &m.rules
+	for indent := 0; indent < r.indent && len(*siblings) > 0; indent++ {
+		siblings = &(*siblings)[len(*siblings)-1].children
+	}
+	*siblings = append(*siblings, r)
+}
+
+// readRule reads a "[indent]>offset=value[&mask][~size][+range]\n" line.
+func readRule(reader *bufio.Reader) (*rule, error) {
+	r := &rule{rangeLen: 1}
+
+	indent, err := reader.ReadString('>')
+	if err != nil {
+		return nil, errMagic
+	}
+	if indent = strings.TrimSuffix(indent, ">"); indent != "" {
+		if r.indent, err = strconv.Atoi(indent); err != nil {
+			return nil, errMagic
+		}
+	}
+
+	offset, err := reader.ReadString('=')
+	if err != nil {
+		return nil, errMagic
+	}
+	if r.offset, err = strconv.Atoi(strings.TrimSuffix(offset, "=")); err != nil {
+		return nil, errMagic
+	}
+
+	var length uint16
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return nil, errMagic
+	}
+	r.value = make([]byte, length)
+	if _, err := io.ReadFull(reader, r.value); err != nil {
+		return nil, errMagic
+	}
+
+	wordSize := 1
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, errMagic
+		}
+		switch b {
+		case '\n':
+			swap(r.value, wordSize)
+			swap(r.mask, wordSize)
+			return r, nil
+		case '&':
+			r.mask = make([]byte, length)
+			if _, err := io.ReadFull(reader, r.mask); err != nil {
+				return nil, errMagic
+			}
+		case '~':
+			if wordSize, err = readNumber(reader); err != nil {
+				return nil, err
+			}
+		case '+':
+			if r.rangeLen, err = readNumber(reader); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errMagic
+		}
+	}
+}
+
+// readNumber reads the decimal number next in reader.
+func readNumber(reader *bufio.Reader) (int, error) {
+	n := 0
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, errMagic
+		}
+		if b < '0' || b > '9' {
+			reader.UnreadByte()
+			return n, nil
+		}
+		n = n*10 + int(b-'0')
+	}
+}
+
+// swap turns words of size bytes written big endian into the byte order of
+// the host, as the content they are matched to is written in.
+func swap(value []byte, size int) {
+	if (size != 2 && size != 4) || len(value)%size != 0 || !littleEndian() {
+		return
+	}
+	for word := 0; word < len(value); word += size {
+		for i, j := word, word+size-1; i < j; i, j = i+1, j-1 {
+			value[i], value[j] = value[j], value[i]
+		}
+	}
+}
+
+func littleEndian() bool {
+	return binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+}
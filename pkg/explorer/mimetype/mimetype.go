@@ -0,0 +1,230 @@
+package mimetype
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Directory, Symlink and Empty are the types of what has no content to
+	// look at
+	Directory = "inode/directory"
+	Symlink   = "inode/symlink"
+	Empty     = "application/x-zerosize"
+	// Binary and Text are the types of files nothing more is known about
+	Binary = "application/octet-stream"
+	Text   = "text/plain"
+)
+
+// sniffSize is how much of a file is read to recognize its content.
+const sniffSize = 4096
+
+// Type is the detected type of a file.
+type Type struct {
+	// MIME is the media type, like image/png
+	MIME string
+	Kind Kind
+	// IsBinary is set for files whose content is not text
+	IsBinary bool
+}
+
+// Comment returns a description of the type as shown to the user, like
+// "PNG image".
+func (t Type) Comment() string {
+	return comment(t.MIME)
+}
+
+// Icons returns the names of the icons of the type in the icon themes, the
+// most specific first, like image-png then image-x-generic.
+func (t Type) Icons() []string {
+	switch t.MIME {
+	case Directory:
+		return []string{"inode-directory", "folder"}
+	case Symlink:
+		return []string{"inode-symlink", "emblem-symbolic-link", "text-x-generic"}
+	}
+
+	var names []string
+	db := database()
+	if db != nil && db.icons[t.MIME] != "" {
+		names = append(names, db.icons[t.MIME])
+	}
+	names = append(names, strings.ReplaceAll(t.MIME, "/", "-"))
+
+	if t.Kind == KindExecutable {
+		names = append(names, "application-x-executable")
+	}
+	if db != nil && db.genericIcons[t.MIME] != "" {
+		names = append(names, db.genericIcons[t.MIME])
+	} else {
+		media, _, _ := strings.Cut(t.MIME, "/")
+		names = append(names, media+"-x-generic")
+	}
+	if !t.IsBinary {
+		names = append(names, "text-x-generic")
+	}
+	return names
+}
+
+// Detect returns the type of the file at path with info. The name is looked
+// at first, and the start of the content when the name tells nothing.
+func Detect(path string, info fs.FileInfo) Type {
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		return Type{MIME: Symlink, Kind: KindSymlink}
+	case info.IsDir():
+		return Type{MIME: Directory, Kind: KindFolder}
+	case !info.Mode().IsRegular():
+		return Type{MIME: Binary, Kind: KindUnknown, IsBinary: true}
+	}
+
+	executable := info.Mode().Perm()&0o111 != 0
+	mime := byName(filepath.Base(path))
+
+	var head []byte
+	if mime == "" || executable {
+		head = sniff(path)
+	}
+	if mime == "" {
+		mime = byContent(head, info.Size())
+	}
+
+	t := Type{MIME: mime, Kind: kindOf(mime), IsBinary: isBinary(mime)}
+	if head != nil {
+		t.IsBinary = bytes.IndexByte(head, 0) >= 0
+	}
+	// a program without a telling name, scripts keep their kind
+	if executable && (t.Kind == KindUnknown || t.Kind == KindBinary) {
+		t.Kind = KindExecutable
+	}
+	return t
+}
+
+// byName returns the type of a file named name from its name alone, or "".
+func byName(name string) string {
+	if db := database(); db != nil {
+		return db.glob(name)
+	}
+	return builtinExtensions[strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))]
+}
+
+// byContent returns the type of a file of size starting with head.
+func byContent(head []byte, size int64) string {
+	if size == 0 {
+		return Empty
+	}
+
+	if db := database(); db != nil {
+		if mime := db.match(head); mime != "" {
+			return mime
+		}
+	} else if mime, _, _ := strings.Cut(http.DetectContentType(head), ";"); mime != Binary && mime != Text {
+		return mime
+	}
+
+	if bytes.IndexByte(head, 0) >= 0 {
+		return Binary
+	}
+	return Text
+}
+
+// sniff returns the start of the content of the file at path, or nil.
+func sniff(path string) []byte {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil
+	}
+	return head[:n]
+}
+
+// isBinary guesses if files of type mime are binary without looking at one.
+func isBinary(mime string) bool {
+	if strings.HasPrefix(mime, "text/") {
+		return false
+	}
+	switch kindOf(mime) {
+	case KindCode, KindText:
+		return false
+	}
+	return !strings.HasSuffix(mime, "+xml") && !strings.HasSuffix(mime, "+json")
+}
+
+// builtinExtensions are the types of common extensions when no database is
+// installed.
+var builtinExtensions = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+	"bmp":  "image/bmp",
+	"webp": "image/webp",
+	"svg":  "image/svg+xml",
+	"ico":  "image/vnd.microsoft.icon",
+	"tif":  "image/tiff",
+	"tiff": "image/tiff",
+	"mp3":  "audio/mpeg",
+	"flac": "audio/flac",
+	"ogg":  "audio/ogg",
+	"wav":  "audio/x-wav",
+	"mp4":  "video/mp4",
+	"mkv":  "video/x-matroska",
+	"webm": "video/webm",
+	"avi":  "video/x-msvideo",
+	"mov":  "video/quicktime",
+	"pdf":  "application/pdf",
+	"txt":  "text/plain",
+	"md":   "text/markdown",
+	"csv":  "text/csv",
+	"doc":  "application/msword",
+	"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"odt":  "application/vnd.oasis.opendocument.text",
+	"xls":  "application/vnd.ms-excel",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ppt":  "application/vnd.ms-powerpoint",
+	"pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"epub": "application/epub+zip",
+	"zip":  "application/zip",
+	"tar":  "application/x-tar",
+	"gz":   "application/gzip",
+	"tgz":  "application/x-compressed-tar",
+	"bz2":  "application/x-bzip2",
+	"xz":   "application/x-xz",
+	"zst":  "application/zstd",
+	"7z":   "application/x-7z-compressed",
+	"rar":  "application/vnd.rar",
+	"deb":  "application/vnd.debian.binary-package",
+	"rpm":  "application/x-rpm",
+	"iso":  "application/x-cd-image",
+	"go":   "text/x-go",
+	"c":    "text/x-csrc",
+	"h":    "text/x-chdr",
+	"cpp":  "text/x-c++src",
+	"rs":   "text/rust",
+	"py":   "text/x-python",
+	"js":   "application/javascript",
+	"ts":   "application/x-typescript",
+	"java": "text/x-java",
+	"sh":   "application/x-shellscript",
+	"html": "text/html",
+	"css":  "text/css",
+	"json": "application/json",
+	"yaml": "application/x-yaml",
+	"yml":  "application/x-yaml",
+	"toml": "application/toml",
+	"xml":  "application/xml",
+	"sql":  "application/sql",
+	"exe":  "application/x-msdownload",
+	"so":   "application/x-sharedlib",
+}
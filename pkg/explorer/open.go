@@ -0,0 +1,82 @@
+package explorer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/skratchdot/open-golang/open"
+)
+
+// OpenAt opens the file at path in the editor of the user with the cursor on
+// line. The editor is taken from $VISUAL or $EDITOR, editors running in a
+// terminal are started in $TERMINAL, and the file is opened with the default
+// application if there is no editor or terminal to use.
+func OpenAt(path string, line int) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	command := editorCommand(editor, os.Getenv("TERMINAL"), path, line)
+	if command == nil {
+		return open.Start(path)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// don't leave a zombie behind, the editor always has a window of its own
+	// so this ends once the user closes it
+	go cmd.Wait()
+	return nil
+}
+
+// editorCommand returns the command opening path at line with editor, in
+// terminal if the editor runs in one. It returns nil if there is no editor,
+// or no terminal for it.
+func editorCommand(editor string, terminal string, path string, line int) []string {
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return nil
+	}
+	name := filepath.Base(fields[0])
+	command := append(fields, editorArgs(name, path, line)...)
+
+	if !inTerminal(name) {
+		return command
+	}
+	terminalFields := strings.Fields(terminal)
+	if len(terminalFields) == 0 {
+		return nil
+	}
+	// -e is what nearly every terminal takes the command to run with
+	return append(append(terminalFields, "-e"), command...)
+}
+
+// inTerminal returns true if the editor named name needs a terminal to run in.
+func inTerminal(name string) bool {
+	switch strings.TrimSuffix(name, ".exe") {
+	case "vi", "vim", "nvim", "nano", "hx", "helix", "micro", "kak", "joe", "ne", "mg":
+		return true
+	default:
+		return false
+	}
+}
+
+// editorArgs returns the arguments opening path at line for the editor named
+// name.
+func editorArgs(name string, path string, line int) []string {
+	switch name {
+	case "code", "codium", "code-insiders":
+		return []string{"--goto", fmt.Sprintf("%s:%d", path, line)}
+	case "subl", "zed", "hx", "helix", "kate":
+		return []string{fmt.Sprintf("%s:%d", path, line)}
+	default:
+		// vi, vim, nvim, emacs, nano, micro, gedit and most others
+		return []string{fmt.Sprintf("+%d", line), path}
+	}
+}
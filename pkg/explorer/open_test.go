@@ -0,0 +1,50 @@
+package explorer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEditorArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"code", []string{"--goto", "/src/main.go:12"}},
+		{"codium", []string{"--goto", "/src/main.go:12"}},
+		{"subl", []string{"/src/main.go:12"}},
+		{"hx", []string{"/src/main.go:12"}},
+		{"vim", []string{"+12", "/src/main.go"}},
+		{"nano", []string{"+12", "/src/main.go"}},
+		{"emacs", []string{"+12", "/src/main.go"}},
+	}
+
+	for _, test := range tests {
+		if got := editorArgs(test.name, "/src/main.go", 12); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		editor   string
+		terminal string
+		want     []string
+	}{
+		{"", "alacritty", nil},
+		{"code --wait", "", []string{"code", "--wait", "--goto", "/src/main.go:12"}},
+		{"/usr/bin/gedit", "alacritty", []string{"/usr/bin/gedit", "+12", "/src/main.go"}},
+		{"nvim", "alacritty", []string{"alacritty", "-e", "nvim", "+12", "/src/main.go"}},
+		{"hx", "xterm -fa Mono", []string{"xterm", "-fa", "Mono", "-e", "hx", "/src/main.go:12"}},
+		// a terminal editor can't be started without a terminal
+		{"/usr/bin/vim", "", nil},
+	}
+
+	for _, test := range tests {
+		got := editorCommand(test.editor, test.terminal, "/src/main.go", 12)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%q in %q: got %q, want %q", test.editor, test.terminal, got, test.want)
+		}
+	}
+}
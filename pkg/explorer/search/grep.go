@@ -0,0 +1,165 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// DefaultMaxFileSize is the size above which files are not searched
+	DefaultMaxFileSize = 10 << 20
+	// sniffSize is how much of a file is looked at to tell if it is binary
+	sniffSize = 8000
+	// maxLineLength is the longest line that is searched, the rest of a file
+	// with a longer line is skipped as it is most likely minified or generated
+	maxLineLength = 64 << 10
+)
+
+// Match is a line of a file matching a content search.
+type Match struct {
+	Path string
+	// Line is the number of the line, starting at 1
+	Line int
+	Text string
+	// Ranges are the byte offsets in Text where the pattern matched
+	Ranges [][]int
+}
+
+// Grep looks for lines matching pattern in the files under root that q
+// matches, and calls found with each of them as soon as they are seen. Files
+// larger than maxSize and binary files are skipped. Files are read by as many
+// workers as there are CPUs, so matches of different files come in no
+// particular order, but those of a file come in order. It stops early when
+// ctx is done, returning its error, or when found returns false.
+func Grep(ctx context.Context, root string, q Query, pattern *regexp.Regexp, maxSize int64, found func(Match) bool) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	paths := make(chan string)
+	var walkErr error
+	go func() {
+		defer close(paths)
+		walkErr = Walk(ctx, root, q, func(path string, info fs.FileInfo) bool {
+			if !info.Mode().IsRegular() || (maxSize > 0 && info.Size() > maxSize) {
+				return true
+			}
+
+			select {
+			case paths <- path:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	// found is called by a single worker at a time
+	var mu sync.Mutex
+	report := func(m Match) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if ctx.Err() != nil {
+			return false
+		}
+		if !found(m) {
+			cancel()
+			return false
+		}
+		return true
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if !grepFile(ctx, path, pattern, report) {
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	// the walk was stopped because found returned false
+	if errors.Is(walkErr, context.Canceled) {
+		return nil
+	}
+	return walkErr
+}
+
+// grepFile reports the lines of the file at path matching pattern. It
+// returns false if the search should stop.
+func grepFile(ctx context.Context, path string, pattern *regexp.Regexp, report func(Match) bool) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, sniffSize)
+	head, err := reader.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return true
+	}
+	if isBinary(head) {
+		return true
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineLength)
+	for line := 1; scanner.Scan(); line++ {
+		if line%1024 == 0 && ctx.Err() != nil {
+			return false
+		}
+
+		text := scanner.Text()
+		ranges := pattern.FindAllStringIndex(text, -1)
+		if ranges == nil {
+			continue
+		}
+
+		if !report(Match{Path: path, Line: line, Text: text, Ranges: ranges}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isBinary guesses from the start of a file if it is binary, text files have
+// no NUL bytes.
+func isBinary(head []byte) bool {
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// GrepPattern compiles what was typed to find in files. Text starting with
+// re: is a regular expression, anything else is looked for as is. The case is
+// ignored unless the text has an upper case letter in it.
+func GrepPattern(text string) (*regexp.Regexp, error) {
+	expression, isRegex := strings.CutPrefix(text, "re:")
+	if !isRegex {
+		expression = regexp.QuoteMeta(text)
+	}
+
+	if !strings.ContainsFunc(text, unicode.IsUpper) {
+		expression = "(?i)" + expression
+	}
+	return regexp.Compile(expression)
+}
@@ -0,0 +1,167 @@
+package search
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a line of a .gitignore file.
+type ignoreRule struct {
+	regex *regexp.Regexp
+	// negate re-includes what earlier rules excluded
+	negate bool
+	// dirOnly rules only match folders
+	dirOnly bool
+}
+
+// ignorer knows the .gitignore rules of the folders walked so far.
+type ignorer struct {
+	rules map[string][]ignoreRule
+}
+
+func newIgnorer() *ignorer {
+	return &ignorer{rules: map[string][]ignoreRule{}}
+}
+
+// load reads the .gitignore file of dir, if it has one.
+func (ig *ignorer) load(dir string) {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) > 0 {
+		ig.rules[dir] = rules
+	}
+}
+
+// ignored returns true if path is excluded by the rules of the folders from
+// root down to its own. Like git, the last matching rule wins and rules of
+// deeper folders come last.
+func (ig *ignorer) ignored(root string, path string, isDir bool) bool {
+	// the folders from root down to the parent of path
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == root || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	ignored := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rules := ig.rules[dirs[i]]
+		if len(rules) == 0 {
+			continue
+		}
+
+		rel, err := filepath.Rel(dirs[i], path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, rule := range rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.regex.MatchString(rel) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// parseIgnoreRule reads a line of a .gitignore file, ok is false for blank
+// lines and comments.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	// escaped leading characters are taken literally
+	line = strings.TrimPrefix(line, "\\")
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// a slash anywhere but at the end anchors the pattern to the folder of
+	// the .gitignore, else it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	prefix := "^(?:.*/)?"
+	if anchored {
+		prefix = "^"
+	}
+
+	regex, err := regexp.Compile(prefix + globToRegex(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.regex = regex
+	return rule, true
+}
+
+// globToRegex translates a .gitignore glob to a regular expression.
+func globToRegex(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
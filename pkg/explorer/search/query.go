@@ -0,0 +1,194 @@
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query describes what a search looks for.
+type Query struct {
+	// Names are globs, or text the name has to contain if they have none of
+	// *?[ in them. A match has to satisfy every one of them.
+	Names []string
+	// Regex, if set, has to match the name
+	Regex *regexp.Regexp
+	// MinSize and MaxSize bound the size of files in bytes, MaxSize is
+	// ignored if negative
+	MinSize int64
+	MaxSize int64
+	// After and Before bound the modification time, zero times are ignored
+	After  time.Time
+	Before time.Time
+	// Hidden searches in hidden files and folders too
+	Hidden bool
+	// Ignored searches in what .gitignore files exclude too
+	Ignored bool
+}
+
+// Parse reads a query from text. Words are name globs, and these terms
+// refine the search:
+//
+//	re:PATTERN      name matches the regular expression
+//	size:>10M       larger than, size:<1k smaller than, size:1M..2G between
+//	after:DATE      modified after DATE, a 2006-01-02 date or a duration ago such as 7d or 12h
+//	before:DATE     modified before DATE
+//	hidden:yes      include hidden files
+//	ignored:yes     include files excluded by .gitignore
+func Parse(text string, now time.Time) (Query, error) {
+	q := Query{MaxSize: -1}
+
+	for _, word := range strings.Fields(text) {
+		name, value, found := strings.Cut(word, ":")
+		if !found {
+			q.Names = append(q.Names, word)
+			continue
+		}
+
+		var err error
+		switch strings.ToLower(name) {
+		case "re":
+			q.Regex, err = regexp.Compile("(?i)" + value)
+		case "size":
+			err = q.parseSize(value)
+		case "after":
+			q.After, err = parseTime(value, now)
+		case "before":
+			q.Before, err = parseTime(value, now)
+		case "hidden":
+			q.Hidden, err = parseYes(value)
+		case "ignored":
+			q.Ignored, err = parseYes(value)
+		default:
+			// not a term, names can have colons in them
+			q.Names = append(q.Names, word)
+		}
+		if err != nil {
+			return Query{}, fmt.Errorf("%s: %w", word, err)
+		}
+	}
+
+	for _, name := range q.Names {
+		if _, err := filepath.Match(name, ""); err != nil {
+			return Query{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return q, nil
+}
+
+// Match returns true if the file named name with info is a result.
+func (q Query) Match(name string, info fs.FileInfo) bool {
+	lower := strings.ToLower(name)
+	for _, pattern := range q.Names {
+		pattern = strings.ToLower(pattern)
+		if strings.ContainsAny(pattern, "*?[") {
+			if matched, _ := filepath.Match(pattern, lower); !matched {
+				return false
+			}
+		} else if !strings.Contains(lower, pattern) {
+			return false
+		}
+	}
+
+	if q.Regex != nil && !q.Regex.MatchString(name) {
+		return false
+	}
+
+	// sizes only make sense for files
+	if q.MinSize > 0 || q.MaxSize >= 0 {
+		if info.IsDir() {
+			return false
+		}
+		if info.Size() < q.MinSize || (q.MaxSize >= 0 && info.Size() > q.MaxSize) {
+			return false
+		}
+	}
+
+	if !q.After.IsZero() && !info.ModTime().After(q.After) {
+		return false
+	}
+	if !q.Before.IsZero() && !info.ModTime().Before(q.Before) {
+		return false
+	}
+
+	return true
+}
+
+// parseSize reads >N, <N or N..M.
+func (q *Query) parseSize(value string) error {
+	var err error
+	switch {
+	case strings.HasPrefix(value, ">"):
+		q.MinSize, err = parseBytes(value[1:])
+		q.MinSize++
+	case strings.HasPrefix(value, "<"):
+		q.MaxSize, err = parseBytes(value[1:])
+		q.MaxSize--
+	case strings.Contains(value, ".."):
+		from, to, _ := strings.Cut(value, "..")
+		if q.MinSize, err = parseBytes(from); err != nil {
+			return err
+		}
+		q.MaxSize, err = parseBytes(to)
+	default:
+		q.MinSize, err = parseBytes(value)
+		q.MaxSize = q.MinSize
+	}
+	return err
+}
+
+// parseBytes reads a size such as 512, 10k or 1.5G.
+func parseBytes(text string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(text), "B")
+
+	multiplier := int64(1)
+	if value != "" {
+		if i := strings.IndexByte("KMGT", value[len(value)-1]); i >= 0 {
+			multiplier = int64(1) << (10 * (i + 1))
+			value = value[:len(value)-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// parseTime reads a 2006-01-02 date or a duration ago such as 7d, 12h or 2w.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	if value != "" {
+		unit := map[byte]time.Duration{
+			'm': time.Minute,
+			'h': time.Hour,
+			'd': 24 * time.Hour,
+			'w': 7 * 24 * time.Hour,
+		}[value[len(value)-1]]
+
+		if number, err := strconv.Atoi(value[:len(value)-1]); err == nil && unit != 0 {
+			return now.Add(-time.Duration(number) * unit), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func parseYes(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "true", "1":
+		return true, nil
+	case "no", "n", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected yes or no, got %q", value)
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/ShakedGold/Gole/pkg/widgets/entry"
+)
+
+// errStop stops the walk when the results are not wanted anymore.
+var errStop = errors.New("search stopped")
+
+// Walk looks for what q describes in the tree under root and calls found
+// with every match as soon as it is seen. It stops early when ctx is done,
+// returning its error, or when found returns false. Folders that can't be
+// read are skipped.
+func Walk(ctx context.Context, root string, q Query, found func(path string, info fs.FileInfo) bool) error {
+	root = filepath.Clean(root)
+	ig := newIgnorer()
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if path == root {
+			if err == nil && !q.Ignored {
+				ig.load(root)
+			}
+			return err
+		}
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		skip := func() error {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		name := d.Name()
+		if !q.Hidden && strings.HasPrefix(name, ".") {
+			return skip()
+		}
+		if !q.Ignored {
+			if name == ".git" || ig.ignored(root, path, d.IsDir()) {
+				return skip()
+			}
+			if d.IsDir() {
+				ig.load(path)
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if q.Match(name, info) && !found(path, info) {
+			return errStop
+		}
+		return nil
+	})
+
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+// View creates the entries the results of a search under root are added to.
+func View(root string) *entry.Entries {
+	results := entry.NewEntries(root, nil)
+	results.Virtual = true
+	return results
+}
+
+// Walker looks for what q describes under root like Walk does, an index can
+// answer faster than the disk.
+type Walker func(ctx context.Context, root string, q Query, found func(path string, info fs.FileInfo) bool) error
+
+// Run searches like Walk and passes every match to add as an entry named
+// after its path from root, so results with the same name can be told
+// apart. It stops when add returns false.
+func Run(ctx context.Context, root string, q Query, add func(entrys ...entry.Entry) bool) error {
+	return RunWith(ctx, Walk, root, q, add)
+}
+
+// RunWith is Run with the matches found by walk.
+func RunWith(ctx context.Context, walk Walker, root string, q Query, add func(entrys ...entry.Entry) bool) error {
+	return walk(ctx, root, q, func(path string, info fs.FileInfo) bool {
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			name = filepath.Base(path)
+		}
+
+		var e entry.Entry
+		if info.IsDir() {
+			e, err = entry.CreateFolder(path, name)
+		} else {
+			e, err = entry.CreateFile(path, name)
+		}
+		if err != nil {
+			// an index can be behind the disk, the file is gone
+			return true
+		}
+
+		return add(e)
+	})
+}
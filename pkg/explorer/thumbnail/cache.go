@@ -0,0 +1,244 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrFailed is returned for files the thumbnail could not be made of
+// before, as recorded in the cache.
+var ErrFailed = errors.New("thumbnail failed before")
+
+// flavor is a size of the thumbnails of the cache, kept in a folder of its
+// own.
+type flavor struct {
+	name string
+	size int
+}
+
+// flavors are the sizes of the cache, the smallest first.
+var flavors = []flavor{
+	{name: "normal", size: 128},
+	{name: "large", size: 256},
+	{name: "x-large", size: 512},
+	{name: "xx-large", size: 1024},
+}
+
+// failFolder is the folder of fail/ recording the files Gole could not make
+// thumbnails of. Its number goes up when files it failed on before may be
+// read now.
+const failFolder = "gole-1"
+
+// textKeys are the keys of the metadata written in the thumbnails, in the
+// order they are written.
+var textKeys = []string{"Thumb::URI", "Thumb::MTime", "Thumb::Size", "Software"}
+
+// Cache is the thumbnail cache of the freedesktop.org thumbnail managing
+// standard, shared with the other applications of the desktop. Thumbnails
+// are PNG files named after the MD5 of the URI of their file, and record
+// when the file was modified so outdated ones are made again.
+type Cache struct {
+	// Dir is the folder of the cache, like ~/.cache/thumbnails
+	Dir string
+}
+
+// DefaultCache returns the cache of the user, in $XDG_CACHE_HOME, or nil if
+// there is no home folder.
+func DefaultCache() *Cache {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return &Cache{Dir: filepath.Join(dir, "thumbnails")}
+}
+
+// Thumbnail returns the thumbnail of the image file at path with its
+// longest side size pixels long, from the cache if it has an up to date one.
+// Otherwise it is made and saved in the smallest flavor at least that large,
+// or the failure is recorded for the file not to be tried again.
+func (c *Cache) Thumbnail(ctx context.Context, path string, size int) (image.Image, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// thumbnails are not made of thumbnails
+	if c.contains(path) {
+		return Make(ctx, path, size)
+	}
+
+	uri := fileURI(path)
+	if img, err := c.Load(uri, info, size); err == nil {
+		return Scale(img, size), nil
+	}
+	if c.failed(uri, info) {
+		return nil, ErrFailed
+	}
+
+	f := flavorFor(size)
+	img, err := Make(ctx, path, f.size)
+	if err != nil {
+		// a file that can't be opened may be readable later
+		var pathErr *fs.PathError
+		if ctx.Err() == nil && !errors.As(err, &pathErr) {
+			c.write(filepath.Join(c.Dir, "fail", failFolder), uri, info, image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+		}
+		return nil, err
+	}
+
+	// the cache is only there to be faster, the thumbnail is fine without
+	c.write(filepath.Join(c.Dir, f.name), uri, info, img)
+	return Scale(img, size), nil
+}
+
+// Load returns the thumbnail of the file at uri with info, from the smallest
+// flavor at least size pixels large having one made since the file was last
+// modified.
+func (c *Cache) Load(uri string, info fs.FileInfo, size int) (image.Image, error) {
+	for _, f := range flavors {
+		if f.size < size && f != flavors[len(flavors)-1] {
+			continue
+		}
+		img, err := c.read(filepath.Join(c.Dir, f.name), uri, info)
+		if err == nil {
+			return img, nil
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// failed tells if making the thumbnail of the file at uri with info failed
+// since it was last modified.
+func (c *Cache) failed(uri string, info fs.FileInfo) bool {
+	_, err := c.read(filepath.Join(c.Dir, "fail", failFolder), uri, info)
+	return err == nil
+}
+
+// read returns the thumbnail in dir of the file at uri with info, if it is
+// up to date.
+func (c *Cache) read(dir string, uri string, info fs.FileInfo) (image.Image, error) {
+	content, err := os.ReadFile(filepath.Join(dir, key(uri)))
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := readText(content)
+	if err != nil {
+		return nil, err
+	}
+	if text["Thumb::URI"] != uri || text["Thumb::MTime"] != strconv.FormatInt(info.ModTime().Unix(), 10) {
+		return nil, fs.ErrNotExist
+	}
+	if size, ok := text["Thumb::Size"]; ok && size != strconv.FormatInt(info.Size(), 10) {
+		return nil, fs.ErrNotExist
+	}
+
+	return png.Decode(bytes.NewReader(content))
+}
+
+// write saves img in dir as the thumbnail of the file at uri with info. It
+// is written to a temporary file first, so other applications never read
+// half of it.
+func (c *Cache) write(dir string, uri string, info fs.FileInfo, img image.Image) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return err
+	}
+	content, err := writeText(encoded.Bytes(), map[string]string{
+		"Thumb::URI":   uri,
+		"Thumb::MTime": strconv.FormatInt(info.ModTime().Unix(), 10),
+		"Thumb::Size":  strconv.FormatInt(info.Size(), 10),
+		"Software":     "Gole",
+	}, textKeys)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "gole-*.png")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, key(uri)))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// contains tells if path is in the cache.
+func (c *Cache) contains(path string) bool {
+	rel, err := filepath.Rel(c.Dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// flavorFor returns the smallest flavor at least size pixels large, or the
+// largest one.
+func flavorFor(size int) flavor {
+	for _, f := range flavors {
+		if f.size >= size {
+			return f
+		}
+	}
+	return flavors[len(flavors)-1]
+}
+
+// fileURI returns the file:// URI of the file at path, escaped like
+// g_filename_to_uri of GLib so the other applications of the desktop find
+// the same thumbnails.
+func fileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	const hex = "0123456789ABCDEF"
+	var uri strings.Builder
+	uri.WriteString("file://")
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; unescaped(c) {
+			uri.WriteByte(c)
+		} else {
+			uri.Write([]byte{'%', hex[c>>4], hex[c&15]})
+		}
+	}
+	return uri.String()
+}
+
+// unescaped returns true for the bytes GLib leaves as they are in the path of
+// a URI.
+func unescaped(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("!$&'()*+,-./:=@_~", c) >= 0
+}
+
+// key returns the name of the thumbnail of the file at uri.
+func key(uri string) string {
+	sum := md5.Sum([]byte(uri))
+	return hex.EncodeToString(sum[:]) + ".png"
+}
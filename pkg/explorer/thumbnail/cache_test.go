@@ -0,0 +1,30 @@
+package thumbnail
+
+import "testing"
+
+func TestFileURI(t *testing.T) {
+	// the URIs are what GLib makes of the paths, and the names the
+	// thumbnails of the other applications of the desktop are stored under
+	tests := []struct {
+		path string
+		uri  string
+		key  string
+	}{
+		{"/home/jens/photos/me.png", "file:///home/jens/photos/me.png", "c6ee772d9e49320e97ec29a7eb5b1697.png"},
+		{"/tmp/uri/IMG (1)!'*.jpg", "file:///tmp/uri/IMG%20(1)!'*.jpg", "40cabeeec543c78a50e5d0422f63447b.png"},
+		{"/tmp/uri/a+b,c;d=e@f$g&h~i.png", "file:///tmp/uri/a+b,c%3Bd=e@f$g&h~i.png", "1ff27527c858f6a72dfd8c516717979f.png"},
+		{"/tmp/uri/x#y?z%w[1]{2}^`|\"<>.jpg", "file:///tmp/uri/x%23y%3Fz%25w%5B1%5D%7B2%7D%5E%60%7C%22%3C%3E.jpg", "516be2d6d0f9dfe547819164714e38c5.png"},
+		{"/tmp/uri/héllo wörld.jpg", "file:///tmp/uri/h%C3%A9llo%20w%C3%B6rld.jpg", "bb20146b228e1801073e2db6e45b341d.png"},
+		{"/tmp/uri/日本.png", "file:///tmp/uri/%E6%97%A5%E6%9C%AC.png", "81c3958e99522af7dd6cdbbe355196f5.png"},
+	}
+
+	for _, test := range tests {
+		uri := fileURI(test.path)
+		if uri != test.uri {
+			t.Errorf("%s: got URI %s, want %s", test.path, uri, test.uri)
+		}
+		if got := key(uri); got != test.key {
+			t.Errorf("%s: got key %s, want %s", test.path, got, test.key)
+		}
+	}
+}
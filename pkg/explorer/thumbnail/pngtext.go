@@ -0,0 +1,81 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// pngSignature starts every PNG file.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+var errNotPNG = errors.New("not a PNG file")
+
+// readText returns the tEXt chunks of the PNG file in content, by keyword.
+// The image/png package skips them.
+func readText(content []byte) (map[string]string, error) {
+	if !bytes.HasPrefix(content, []byte(pngSignature)) {
+		return nil, errNotPNG
+	}
+
+	text := map[string]string{}
+	for rest := content[len(pngSignature):]; len(rest) >= 12; {
+		length := int(binary.BigEndian.Uint32(rest))
+		if length > len(rest)-12 {
+			return nil, errNotPNG
+		}
+		kind, data := string(rest[4:8]), rest[8:8+length]
+		rest = rest[12+length:]
+
+		switch kind {
+		case "tEXt":
+			if keyword, value, found := bytes.Cut(data, []byte{0}); found {
+				text[string(keyword)] = string(value)
+			}
+		case "IEND":
+			return text, nil
+		}
+	}
+	return text, nil
+}
+
+// writeText returns the PNG file in content with tEXt chunks holding text
+// added right after its header, where readers look first.
+func writeText(content []byte, text map[string]string, keywords []string) ([]byte, error) {
+	// the signature and the IHDR chunk, 13 bytes long
+	const header = len(pngSignature) + 12 + 13
+	if len(content) < header || !bytes.HasPrefix(content, []byte(pngSignature)) {
+		return nil, errNotPNG
+	}
+
+	var out bytes.Buffer
+	out.Write(content[:header])
+	for _, keyword := range keywords {
+		value, ok := text[keyword]
+		if !ok {
+			continue
+		}
+		data := append([]byte(keyword+"\x00"), value...)
+		writeChunk(&out, "tEXt", data)
+	}
+	out.Write(content[header:])
+	return out.Bytes(), nil
+}
+
+// writeChunk writes the PNG chunk of type kind holding data.
+func writeChunk(out *bytes.Buffer, kind string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	out.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	out.WriteString(kind)
+	out.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	out.Write(sum[:])
+}
@@ -0,0 +1,145 @@
+package thumbnail
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+)
+
+// Pool makes thumbnails in the background, a bounded number at a time. The
+// last requested are made first, as they are the ones just scrolled to. It
+// is safe for concurrent use.
+type Pool struct {
+	// Cache, if set, is where thumbnails are read from and saved to. It is
+	// set before the first request.
+	Cache *Cache
+
+	workers int
+	done    func(path string, img image.Image, err error)
+
+	mu sync.Mutex
+	// queue holds the requests not started yet, the newest last
+	queue []*job
+	// jobs are the queued and running requests by path
+	jobs    map[string]*job
+	running int
+}
+
+// job is a request for the thumbnail of a file.
+type job struct {
+	path   string
+	size   int
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewPool returns a pool making thumbnails with at most workers goroutines,
+// or one per CPU up to 4 if workers is 0. done is called from the worker
+// with every thumbnail made, or the error making it, unless it was cancelled.
+func NewPool(workers int, done func(path string, img image.Image, err error)) *Pool {
+	if workers <= 0 {
+		workers = min(runtime.NumCPU(), 4)
+	}
+	return &Pool{
+		workers: workers,
+		done:    done,
+		jobs:    map[string]*job{},
+	}
+}
+
+// Request asks for the thumbnail of the image file at path, with its longest
+// side size pixels long. A file already requested is not requested again.
+func (p *Pool) Request(path string, size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.jobs[path]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{path: path, size: size, ctx: ctx, cancel: cancel}
+	p.jobs[path] = j
+	p.queue = append(p.queue, j)
+
+	if p.running < p.workers {
+		p.running++
+		go p.work()
+	}
+}
+
+// Requested tells if the thumbnail of the file at path is queued or being
+// made.
+func (p *Pool) Requested(path string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.jobs[path]
+	return ok
+}
+
+// Retain cancels the requests of the files keep returns false for, stopping
+// the thumbnails being made for them.
+func (p *Pool) Retain(keep func(path string) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue := p.queue[:0]
+	for _, j := range p.queue {
+		if keep(j.path) {
+			queue = append(queue, j)
+		}
+	}
+	clear(p.queue[len(queue):])
+	p.queue = queue
+
+	for path, j := range p.jobs {
+		if !keep(path) {
+			j.cancel()
+			delete(p.jobs, path)
+		}
+	}
+}
+
+// CancelAll cancels every request.
+func (p *Pool) CancelAll() {
+	p.Retain(func(string) bool { return false })
+}
+
+// makeThumbnail returns the thumbnail j asks for, from the cache if there is one.
+func (p *Pool) makeThumbnail(j *job) (image.Image, error) {
+	if p.Cache == nil {
+		return Make(j.ctx, j.path, j.size)
+	}
+	return p.Cache.Thumbnail(j.ctx, j.path, j.size)
+}
+
+// work makes the requested thumbnails until there are none left.
+func (p *Pool) work() {
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.running--
+			p.mu.Unlock()
+			return
+		}
+		j := p.queue[len(p.queue)-1]
+		p.queue[len(p.queue)-1] = nil
+		p.queue = p.queue[:len(p.queue)-1]
+		p.mu.Unlock()
+
+		img, err := p.makeThumbnail(j)
+
+		p.mu.Lock()
+		current := p.jobs[j.path] == j
+		if current {
+			delete(p.jobs, j.path)
+		}
+		p.mu.Unlock()
+		j.cancel()
+
+		if current && p.done != nil {
+			p.done(j.path, img, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+
+	"golang.org/x/image/draw"
+
+	// decoders of the formats thumbnails are made of
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+// maxPixels is the most pixels the images thumbnails are made of can have,
+// decoding a larger one would take gigabytes of memory for a few kilobytes
+// of thumbnail.
+const maxPixels = 100_000_000
+
+// ErrTooLarge is returned for images with more pixels than thumbnails are
+// made of.
+var ErrTooLarge = errors.New("the image is too large")
+
+// types are the types of the files thumbnails are made of.
+var types = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/bmp":  true,
+	"image/webp": true,
+}
+
+// Supported tells if thumbnails are made of files of type mime.
+func Supported(mime string) bool {
+	return types[mime]
+}
+
+// Make returns the thumbnail of the image file at path, with its longest
+// side size pixels long. Images smaller than that are returned as they are,
+// images with more than maxPixels pixels are not decoded. Reading the file
+// stops when ctx is done.
+func Make(ctx context.Context, path string, size int) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the header tells the size before the pixels are read
+	config, _, err := image.DecodeConfig(&contextReader{ctx: ctx, r: file})
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(&contextReader{ctx: ctx, r: file})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return Scale(img, size), nil
+}
+
+// Scale returns img with its longest side size pixels long, or img itself if
+// it is not larger than that. Large images are first brought down to four
+// times size with a cheap filter, so the smooth one only works on a few times
+// the pixels it makes.
+func Scale(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	longest := max(bounds.Dx(), bounds.Dy())
+	if size <= 0 || longest <= size {
+		return img
+	}
+
+	if longest > 4*size {
+		img = resize(img, draw.ApproxBiLinear, 4*size)
+	}
+	return resize(img, draw.CatmullRom, size)
+}
+
+// resize scales img down to have its longest side size pixels long with
+// scaler.
+func resize(img image.Image, scaler draw.Scaler, size int) image.Image {
+	bounds := img.Bounds()
+	longest := max(bounds.Dx(), bounds.Dy())
+	width := max(bounds.Dx()*size/longest, 1)
+	height := max(bounds.Dy()*size/longest, 1)
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaler.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
+
+// contextReader fails reading once its context is done, so a decoder stops
+// early.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
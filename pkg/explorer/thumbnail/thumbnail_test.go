@@ -0,0 +1,50 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	dir := t.TempDir()
+
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewNRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	// only the header of a GIF of 20000 by 20000 pixels, which is all that
+	// is read of it
+	huge := []byte("GIF89a\x20\x4e\x20\x4e\x00\x00\x00")
+
+	tests := []struct {
+		name    string
+		content []byte
+		size    image.Point
+		err     error
+	}{
+		{"small.png", small.Bytes(), image.Pt(10, 5), nil},
+		{"huge.gif", huge, image.Point{}, ErrTooLarge},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, test.content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := Make(context.Background(), path, 10)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if err == nil && img.Bounds().Size() != test.size {
+			t.Errorf("%s: got size %v, want %v", test.name, img.Bounds().Size(), test.size)
+		}
+	}
+}
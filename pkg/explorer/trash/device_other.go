@@ -0,0 +1,8 @@
+//go:build !unix
+
+package trash
+
+// device is not supported here, every file goes to the home trash.
+func device(path string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,85 @@
+package trash
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	infoHeader = "[Trash Info]"
+	infoSuffix = ".trashinfo"
+	dateLayout = "2006-01-02T15:04:05"
+)
+
+var ErrInvalidInfo = errors.New("invalid trashinfo file")
+
+// info is the content of a .trashinfo file.
+type info struct {
+	// Path is the escaped original path, either absolute or relative to the
+	// folder the trash is in
+	Path         string
+	DeletionDate time.Time
+}
+
+// encodeInfo returns the content of a .trashinfo file for path.
+func encodeInfo(path string, deletionDate time.Time) string {
+	escaped := (&url.URL{Path: path}).EscapedPath()
+
+	return fmt.Sprintf("%s\nPath=%s\nDeletionDate=%s\n", infoHeader, escaped, deletionDate.Format(dateLayout))
+}
+
+// decodeInfo parses a .trashinfo file.
+func decodeInfo(r io.Reader) (info, error) {
+	var result info
+	var inGroup bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			inGroup = line == infoHeader
+			continue
+		}
+		if !inGroup {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "Path":
+			path, err := url.PathUnescape(strings.TrimSpace(value))
+			if err != nil {
+				return info{}, fmt.Errorf("%w: %v", ErrInvalidInfo, err)
+			}
+			result.Path = path
+		case "DeletionDate":
+			// a broken date is not a reason to hide the file
+			date, err := time.ParseInLocation(dateLayout, strings.TrimSpace(value), time.Local)
+			if err == nil {
+				result.DeletionDate = date
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return info{}, err
+	}
+
+	if result.Path == "" {
+		return info{}, fmt.Errorf("%w: missing Path", ErrInvalidInfo)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package trash
+
+import (
+	"errors"
+	"os"
+)
+
+// renameExclusive renames oldpath to newpath unless newpath exists, for
+// systems without an atomic no-replace rename. Linking a file fails if the
+// name is taken, folders can't be linked so they are checked just before.
+func renameExclusive(oldpath string, newpath string) error {
+	info, err := os.Lstat(oldpath)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		err := os.Link(oldpath, newpath)
+		if err == nil {
+			return os.Remove(oldpath)
+		}
+		if errors.Is(err, os.ErrExist) {
+			return err
+		}
+		// the filesystem may not support hard links
+	}
+
+	if _, err := os.Lstat(newpath); err == nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrExist}
+	}
+	return os.Rename(oldpath, newpath)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package trash
+
+// renameNoReplace renames oldpath to newpath and fails if newpath exists.
+func renameNoReplace(oldpath string, newpath string) error {
+	return renameExclusive(oldpath, newpath)
+}
@@ -0,0 +1,496 @@
+package trash
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoTrash = errors.New("no trash available for this location")
+	ErrExists  = errors.New("the original location is taken")
+)
+
+// Trash is a single trash directory as described by the freedesktop.org
+// Trash specification.
+type Trash struct {
+	// Dir holds the files and info folders
+	Dir string
+	// Top is the folder relative paths are resolved against, it is empty
+	// for the home trash
+	Top string
+}
+
+// Item is a file or folder inside a trash.
+type Item struct {
+	// Name is the name of the file inside the trash
+	Name string
+	// Path is the absolute path the file was trashed from
+	Path         string
+	DeletionDate time.Time
+	Trash        *Trash
+}
+
+// File returns the path of the trashed file.
+func (i Item) File() string {
+	return filepath.Join(i.Trash.Dir, "files", i.Name)
+}
+
+func (i Item) infoPath() string {
+	return filepath.Join(i.Trash.Dir, "info", i.Name+infoSuffix)
+}
+
+// Home returns the trash in $XDG_DATA_HOME.
+func Home() (*Trash, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" || !filepath.IsAbs(dataHome) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+
+	return &Trash{
+		Dir: filepath.Join(dataHome, "Trash"),
+	}, nil
+}
+
+// For returns the trash path should be moved to. Files on the same device as
+// the home trash use it, others use the trash at the top of their mount.
+func For(path string) (*Trash, error) {
+	home, err := Home()
+	if err != nil {
+		return nil, err
+	}
+
+	pathDevice, ok := device(path)
+	if !ok {
+		return home, nil
+	}
+	if homeDevice, ok := device(existingParent(home.Dir)); ok && homeDevice == pathDevice {
+		return home, nil
+	}
+
+	top := topDir(path, pathDevice)
+	uid := strconv.Itoa(os.Getuid())
+
+	if shared(top) {
+		dir := filepath.Join(top, ".Trash", uid)
+		if err := os.MkdirAll(dir, 0o700); err == nil {
+			return &Trash{Dir: dir, Top: top}, nil
+		}
+	}
+
+	dir := filepath.Join(top, ".Trash-"+uid)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoTrash, err)
+	}
+	if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a folder", ErrNoTrash, dir)
+	}
+
+	return &Trash{Dir: dir, Top: top}, nil
+}
+
+// Trashes returns the home trash and every trash found at the top of a
+// mounted filesystem.
+func Trashes() ([]*Trash, error) {
+	home, err := Home()
+	if err != nil {
+		return nil, err
+	}
+
+	trashes := []*Trash{home}
+	uid := strconv.Itoa(os.Getuid())
+
+	for _, top := range mounts() {
+		// like For, a $topdir/.Trash that isn't set up properly is not used
+		var candidates []string
+		if shared(top) {
+			candidates = append(candidates, filepath.Join(top, ".Trash", uid))
+		}
+		candidates = append(candidates, filepath.Join(top, ".Trash-"+uid))
+		for _, dir := range candidates {
+			if dir == home.Dir {
+				continue
+			}
+			if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+				trashes = append(trashes, &Trash{Dir: dir, Top: top})
+			}
+		}
+	}
+
+	return trashes, nil
+}
+
+// Put moves path to the trash and returns the trashed item.
+func Put(path string) (Item, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Item{}, err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return Item{}, err
+	}
+
+	trash, err := For(path)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return trash.Put(path)
+}
+
+// Put moves path into this trash.
+func (t *Trash) Put(path string) (Item, error) {
+	for _, dir := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(t.Dir, dir), 0o700); err != nil {
+			return Item{}, err
+		}
+	}
+
+	recorded := path
+	if t.Top != "" {
+		if rel, ok := below(t.Top, path); ok {
+			recorded = rel
+		}
+	}
+
+	item := Item{
+		Path:         path,
+		DeletionDate: time.Now(),
+		Trash:        t,
+	}
+
+	// creating the info file with O_EXCL reserves the name atomically
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	if ext == base {
+		ext = ""
+	}
+	for i := 1; ; i++ {
+		item.Name = base
+		if i > 1 {
+			item.Name = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(base, ext), i, ext)
+		}
+
+		file, err := os.OpenFile(item.infoPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return Item{}, err
+		}
+
+		_, err = file.WriteString(encodeInfo(recorded, item.DeletionDate))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(item.infoPath())
+			return Item{}, err
+		}
+
+		// a file may exist without an info file after a crash
+		if _, err := os.Lstat(item.File()); err == nil {
+			os.Remove(item.infoPath())
+			continue
+		}
+		break
+	}
+
+	if err := os.Rename(path, item.File()); err != nil {
+		os.Remove(item.infoPath())
+		return Item{}, err
+	}
+
+	return item, nil
+}
+
+// Items returns the content of every trash, most recently deleted first.
+func Items() ([]Item, error) {
+	trashes, err := Trashes()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, trash := range trashes {
+		trashItems, err := trash.Items()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, trashItems...)
+	}
+
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].DeletionDate.After(items[b].DeletionDate)
+	})
+
+	return items, nil
+}
+
+// Items returns the content of this trash.
+func (t *Trash) Items() ([]Item, error) {
+	infos, err := os.ReadDir(filepath.Join(t.Dir, "info"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, infoEntry := range infos {
+		name, ok := strings.CutSuffix(infoEntry.Name(), infoSuffix)
+		if !ok || infoEntry.IsDir() {
+			continue
+		}
+
+		item, err := t.item(name)
+		if err != nil {
+			// skip broken records, other applications may have left them
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Lookup returns the item whose trashed file is path.
+func Lookup(path string) (Item, bool) {
+	trashes, err := Trashes()
+	if err != nil {
+		return Item{}, false
+	}
+
+	for _, trash := range trashes {
+		files := filepath.Join(trash.Dir, "files")
+		if filepath.Dir(path) != files {
+			continue
+		}
+
+		item, err := trash.item(filepath.Base(path))
+		if err != nil {
+			return Item{}, false
+		}
+		return item, true
+	}
+
+	return Item{}, false
+}
+
+// IsTrash returns true if path is the files folder of the home trash.
+func IsTrash(path string) bool {
+	home, err := Home()
+	if err != nil {
+		return false
+	}
+
+	return filepath.Clean(path) == filepath.Join(home.Dir, "files")
+}
+
+// Restore moves the item back to where it was deleted from and returns that
+// path. It refuses to overwrite anything that is there now.
+func Restore(item Item) (string, error) {
+	if _, err := os.Lstat(item.Path); err == nil {
+		return "", fmt.Errorf("%w: %s", ErrExists, item.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(item.Path), 0o755); err != nil {
+		return "", err
+	}
+	// something may have been created there since the check above
+	err := renameNoReplace(item.File(), item.Path)
+	if errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("%w: %s", ErrExists, item.Path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return item.Path, os.Remove(item.infoPath())
+}
+
+// Remove permanently deletes the item from the trash.
+func Remove(item Item) error {
+	if err := os.RemoveAll(item.File()); err != nil {
+		return err
+	}
+
+	err := os.Remove(item.infoPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Empty permanently deletes the content of every trash.
+func Empty() error {
+	trashes, err := Trashes()
+	if err != nil {
+		return err
+	}
+
+	for _, trash := range trashes {
+		if err := trash.Empty(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Empty permanently deletes the content of this trash.
+func (t *Trash) Empty() error {
+	// remove the files first so an interrupted empty never orphans them
+	for _, dir := range []string{"files", "info"} {
+		children, err := os.ReadDir(filepath.Join(t.Dir, dir))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			if err := os.RemoveAll(filepath.Join(t.Dir, dir, child.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := os.Remove(filepath.Join(t.Dir, "directorysizes"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// item reads the info file of the trashed file called name.
+func (t *Trash) item(name string) (Item, error) {
+	item := Item{
+		Name:  name,
+		Trash: t,
+	}
+
+	file, err := os.Open(item.infoPath())
+	if err != nil {
+		return Item{}, err
+	}
+	defer file.Close()
+
+	record, err := decodeInfo(file)
+	if err != nil {
+		return Item{}, err
+	}
+
+	item.Path = record.Path
+	if !filepath.IsAbs(item.Path) {
+		// a relative path can't take a restore off the filesystem of the trash
+		if _, ok := below(t.Top, filepath.Join(t.Top, item.Path)); t.Top == "" || !ok {
+			return Item{}, fmt.Errorf("%w: %s is not under %s", ErrInvalidInfo, record.Path, t.Top)
+		}
+		item.Path = filepath.Join(t.Top, item.Path)
+	}
+	item.DeletionDate = record.DeletionDate
+
+	return item, nil
+}
+
+// below returns path relative to dir, or false if it is not under dir.
+func below(dir string, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// shared returns true if $topdir/.Trash can hold the trashes of the users,
+// which the specification only allows for a sticky folder set up by an
+// administrator. Lstat makes sure it is not a symbolic link.
+func shared(top string) bool {
+	info, err := os.Lstat(filepath.Join(top, ".Trash"))
+	return err == nil && info.IsDir() && info.Mode()&fs.ModeSticky != 0
+}
+
+// existingParent returns path or the closest parent of it that exists.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Lstat(path); err == nil {
+			return path
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// topDir returns the mount point path is on, the highest parent still on
+// the same device.
+func topDir(path string, pathDevice uint64) string {
+	dir := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+
+		parentDevice, ok := device(parent)
+		if !ok || parentDevice != pathDevice {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// mounts returns the mount points of the system, where /proc is available.
+func mounts() []string {
+	file, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var points []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		points = append(points, unescapeMount(fields[1]))
+	}
+
+	return points
+}
+
+// unescapeMount decodes the octal escapes used for spaces and tabs in
+// /proc/self/mounts.
+func unescapeMount(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
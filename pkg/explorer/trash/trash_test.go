@@ -0,0 +1,138 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTrash returns an empty trash at the top of a temporary folder, like
+// the one of a removable drive.
+func newTrash(t *testing.T) *Trash {
+	t.Helper()
+
+	top := t.TempDir()
+	return &Trash{Dir: filepath.Join(top, ".Trash-1000"), Top: top}
+}
+
+// write creates a file with content, and the folders it is in.
+func write(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	trash := newTrash(t)
+	path := filepath.Join(trash.Top, "folder", "file.txt")
+	write(t, path, "content")
+
+	item, err := trash.Put(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("%s is still there after it was trashed", path)
+	}
+
+	items, err := trash.Items()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Path != path {
+		t.Fatalf("got items %+v, want one for %s", items, path)
+	}
+
+	restored, err := Restore(items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != path {
+		t.Fatalf("restored to %s, want %s", restored, path)
+	}
+	if _, err := os.Lstat(item.File()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("the trashed file is still in the trash")
+	}
+}
+
+func TestRestoreRefusesToOverwrite(t *testing.T) {
+	trash := newTrash(t)
+	path := filepath.Join(trash.Top, "file.txt")
+	write(t, path, "trashed")
+
+	item, err := trash.Put(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a new file took the name since
+	write(t, path, "new")
+
+	if _, err := Restore(item); !errors.Is(err, ErrExists) {
+		t.Fatalf("got error %v, want %v", err, ErrExists)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "new" {
+		t.Fatalf("the new file has %q, it was overwritten", content)
+	}
+	if content, _ := os.ReadFile(item.File()); string(content) != "trashed" {
+		t.Fatalf("the trashed file has %q, it was lost", content)
+	}
+
+	// the check and the rename are one step, a file showing up in between
+	// is not replaced either
+	if err := renameNoReplace(item.File(), path); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("got error %v, want %v", err, os.ErrExist)
+	}
+}
+
+func TestRelativePathOutsideTop(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"inside", "folder/file.txt", true},
+		{"absolute", "/tmp/file.txt", true},
+		{"parent", "../../home/user/.bashrc", false},
+		{"through inside", "folder/../../file.txt", false},
+		{"dots in name", "..file.txt", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trash := newTrash(t)
+			write(t, filepath.Join(trash.Dir, "files", "file.txt"), "content")
+			write(t, filepath.Join(trash.Dir, "info", "file.txt"+infoSuffix), "[Trash Info]\nPath="+test.path+"\nDeletionDate=2024-01-02T03:04:05\n")
+
+			item, err := trash.item("file.txt")
+			if test.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidInfo) {
+				t.Fatalf("got item %+v and error %v, want %v", item, err, ErrInvalidInfo)
+			}
+			// the broken record is skipped, so it can't be restored
+			if items, err := trash.Items(); err != nil || len(items) != 0 {
+				t.Fatalf("got items %+v and error %v, want none", items, err)
+			}
+		})
+	}
+}
+
+func TestRelativePathInHomeTrash(t *testing.T) {
+	trash := &Trash{Dir: t.TempDir()}
+	write(t, filepath.Join(trash.Dir, "info", "file.txt"+infoSuffix), "[Trash Info]\nPath=file.txt\n")
+
+	if _, err := trash.item("file.txt"); !errors.Is(err, ErrInvalidInfo) {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidInfo)
+	}
+}
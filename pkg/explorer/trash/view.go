@@ -0,0 +1,45 @@
+package trash
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ShakedGold/Gole/pkg/widgets/entry"
+)
+
+// View returns the content of every trash as entries. Each entry points at
+// the trashed file and is named after the file it used to be.
+func View() (*entry.Entries, error) {
+	home, err := Home()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := Items()
+	if err != nil {
+		return nil, err
+	}
+
+	entrys := make([]entry.Entry, 0, len(items))
+	for _, item := range items {
+		e, err := itemEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		entrys = append(entrys, e)
+	}
+
+	view := entry.NewEntries(filepath.Join(home.Dir, "files"), entrys)
+	// the entries come from every trash, not only the one at the path
+	view.Virtual = true
+	return view, nil
+}
+
+func itemEntry(item Item) (entry.Entry, error) {
+	alias := filepath.Base(item.Path)
+	if info, err := os.Stat(item.File()); err == nil && info.IsDir() {
+		return entry.CreateFolder(item.File(), alias)
+	}
+
+	return entry.CreateFile(item.File(), alias)
+}
@@ -0,0 +1,457 @@
+package entry
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+
+	"gioui.org/gesture"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+)
+
+// Column is a column of the details view.
+type Column int
+
+const (
+	ColumnName Column = iota
+	ColumnSize
+	ColumnModified
+	ColumnType
+	ColumnPermissions
+	ColumnOwner
+)
+
+// Columns lists every column of the details view.
+var Columns = []Column{
+	ColumnName,
+	ColumnSize,
+	ColumnModified,
+	ColumnType,
+	ColumnPermissions,
+	ColumnOwner,
+}
+
+// Title returns the header of the column.
+func (c Column) Title() string {
+	switch c {
+	case ColumnSize:
+		return "Size"
+	case ColumnModified:
+		return "Modified"
+	case ColumnType:
+		return "Type"
+	case ColumnPermissions:
+		return "Permissions"
+	case ColumnOwner:
+		return "Owner"
+	default:
+		return "Name"
+	}
+}
+
+// SortBy returns the order of the entries when sorting by the column.
+func (c Column) SortBy() SortBy {
+	switch c {
+	case ColumnSize:
+		return SortBySize
+	case ColumnModified:
+		return SortByModified
+	case ColumnType:
+		return SortByType
+	case ColumnPermissions:
+		return SortByPermissions
+	case ColumnOwner:
+		return SortByOwner
+	default:
+		return SortByName
+	}
+}
+
+// Value returns what the column shows for e.
+func (c Column) Value(e Entry) string {
+	switch c {
+	case ColumnSize:
+		if e.IsFolder {
+			return ""
+		}
+		return FormatSize(e.Size())
+	case ColumnModified:
+		return FormatTime(e.ModTime())
+	case ColumnType:
+		return e.TypeName()
+	case ColumnPermissions:
+		return e.Permissions()
+	case ColumnOwner:
+		return e.Owner()
+	default:
+		return e.Name()
+	}
+}
+
+// DetailsColumn is the state of a single column of the details view.
+type DetailsColumn struct {
+	Column Column  `json:"column"`
+	Width  unit.Dp `json:"width"`
+	Hidden bool    `json:"hidden"`
+
+	header header
+}
+
+// header handles dragging a column header to reorder it and dragging its
+// edge to resize it.
+type header struct {
+	move   gesture.Drag
+	resize gesture.Drag
+	// moveStart is where the pointer was pressed on the header
+	moveStart float32
+	// resizeFrom is where the resize handle was grabbed
+	resizeFrom float32
+	// x is where the header was last laid out, for dropping moved headers
+	x int
+}
+
+// Details is the layout of the details view. The column order, widths and
+// hidden columns are stored so they are remembered between runs.
+type Details struct {
+	Columns []*DetailsColumn `json:"columns"`
+
+	path string
+}
+
+const (
+	minColumnWidth = unit.Dp(40)
+	resizeHandle   = unit.Dp(6)
+	// moveSlop is how far a header has to be dragged before it moves
+	moveSlop = unit.Dp(8)
+)
+
+// NewDetails creates the default details layout.
+func NewDetails() *Details {
+	return &Details{
+		Columns: []*DetailsColumn{
+			{Column: ColumnName, Width: 300},
+			{Column: ColumnSize, Width: 90},
+			{Column: ColumnModified, Width: 140},
+			{Column: ColumnType, Width: 110},
+			{Column: ColumnPermissions, Width: 110},
+			{Column: ColumnOwner, Width: 90},
+		},
+	}
+}
+
+// DefaultDetails loads the details layout from the user's config folder.
+func DefaultDetails() (*Details, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadDetails(filepath.Join(configDir, "gole", "details.json"))
+}
+
+// LoadDetails loads the details layout stored at path, a missing file gives
+// the default layout.
+func LoadDetails(path string) (*Details, error) {
+	details := NewDetails()
+	details.path = path
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return details, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &Details{}
+	if err := json.Unmarshal(content, stored); err != nil {
+		return nil, err
+	}
+
+	// only keep known columns, and add any new column at the end
+	known := map[Column]bool{}
+	var columns []*DetailsColumn
+	for _, c := range stored.Columns {
+		if c == nil || c.Column < ColumnName || c.Column > ColumnOwner || known[c.Column] {
+			continue
+		}
+		if c.Width < minColumnWidth {
+			c.Width = minColumnWidth
+		}
+		known[c.Column] = true
+		columns = append(columns, c)
+	}
+	for _, c := range details.Columns {
+		if !known[c.Column] {
+			columns = append(columns, c)
+		}
+	}
+	details.Columns = columns
+
+	return details, nil
+}
+
+// Save stores the details layout.
+func (d *Details) Save() error {
+	if d.path == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(d.path, content, 0o644)
+}
+
+// Toggle hides or shows column. The name column is always shown.
+func (d *Details) Toggle(column Column) {
+	if column == ColumnName {
+		return
+	}
+
+	for _, c := range d.Columns {
+		if c.Column == column {
+			c.Hidden = !c.Hidden
+		}
+	}
+	d.Save()
+}
+
+// IsHidden returns true if column is hidden.
+func (d *Details) IsHidden(column Column) bool {
+	for _, c := range d.Columns {
+		if c.Column == column {
+			return c.Hidden
+		}
+	}
+	return false
+}
+
+// visible returns the shown columns in order.
+func (d *Details) visible() []*DetailsColumn {
+	var columns []*DetailsColumn
+	for _, c := range d.Columns {
+		if !c.Hidden {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// move puts column c where x is in the header.
+func (d *Details) move(c *DetailsColumn, x int) {
+	var target *DetailsColumn
+	for _, other := range d.visible() {
+		if x >= other.header.x {
+			target = other
+		}
+	}
+	if target == nil || target == c {
+		return
+	}
+
+	// reorder in the full list, hidden columns keep their place
+	after := c.header.x < target.header.x
+	var columns []*DetailsColumn
+	for _, other := range d.Columns {
+		if other == c {
+			continue
+		}
+		if other == target && !after {
+			columns = append(columns, c)
+		}
+		columns = append(columns, other)
+		if other == target && after {
+			columns = append(columns, c)
+		}
+	}
+	d.Columns = columns
+	d.Save()
+}
+
+// layoutHeader draws the column headers and handles sorting, resizing and
+// reordering.
+func (entries *Entries) layoutHeader(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	d := entries.Details
+
+	var children []layout.FlexChild
+	x := 0
+	for _, c := range d.visible() {
+		c := c
+		c.header.x = x
+		x += gtx.Dp(c.Width)
+
+		entries.updateHeader(gtx, c)
+
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return entries.layoutHeaderCell(gtx, theme, c)
+		}))
+	}
+
+	dims := layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+
+	// a line between the header and the rows
+	line := clip.Rect{Min: image.Point{Y: dims.Size.Y - 1}, Max: image.Point{X: gtx.Constraints.Max.X, Y: dims.Size.Y}}
+	paint.FillShape(gtx.Ops, theme.Palette.ContrastBg, line.Op())
+
+	return dims
+}
+
+// updateHeader handles the pointer events of a header.
+func (entries *Entries) updateHeader(gtx layout.Context, c *DetailsColumn) {
+	d := entries.Details
+	h := &c.header
+
+	// every event of a frame is relative to the handle as it was last laid
+	// out, so widths are computed from the width at that time
+	width := c.Width
+	for {
+		e, ok := h.resize.Update(gtx.Metric, gtx.Source, gesture.Horizontal)
+		if !ok {
+			break
+		}
+
+		switch e.Kind {
+		case pointer.Press:
+			h.resizeFrom = e.Position.X
+		case pointer.Drag:
+			c.Width = width + unit.Dp((e.Position.X-h.resizeFrom)/gtx.Metric.PxPerDp)
+			if c.Width < minColumnWidth {
+				c.Width = minColumnWidth
+			}
+		case pointer.Release:
+			d.Save()
+		}
+	}
+
+	for {
+		e, ok := h.move.Update(gtx.Metric, gtx.Source, gesture.Horizontal)
+		if !ok {
+			break
+		}
+
+		switch e.Kind {
+		case pointer.Press:
+			h.moveStart = e.Position.X
+		case pointer.Release:
+			moved := e.Position.X - h.moveStart
+			if moved < 0 {
+				moved = -moved
+			}
+
+			// a press and release in place is a click
+			if moved < float32(gtx.Dp(moveSlop)) {
+				entries.sortBy(c.Column)
+			} else {
+				d.move(c, h.x+int(e.Position.X))
+			}
+		}
+	}
+}
+
+// sortBy sorts the entries by column, clicking the same column again
+// reverses the order.
+func (entries *Entries) sortBy(column Column) {
+	entries.Sorter.SortByColumn(column)
+	entries.Prepare()
+}
+
+func (entries *Entries) layoutHeaderCell(gtx layout.Context, theme *material.Theme, c *DetailsColumn) layout.Dimensions {
+	h := &c.header
+	width := gtx.Dp(c.Width)
+	gtx.Constraints = layout.Exact(image.Point{X: width, Y: gtx.Constraints.Max.Y})
+	gtx.Constraints.Min.Y = 0
+
+	title := c.Column.Title()
+	if entries.Sorter.By == c.Column.SortBy() {
+		if entries.Sorter.Descending {
+			title += " ▼"
+		} else {
+			title += " ▲"
+		}
+	}
+
+	macro := op.Record(gtx.Ops)
+	dims := layout.UniformInset(unit.Dp(6)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		label := material.Body2(theme, title)
+		label.MaxLines = 1
+		label.Font.Weight = 600
+		return label.Layout(gtx)
+	})
+	call := macro.Stop()
+	dims.Size.X = width
+
+	// the whole header can be dragged to reorder
+	area := clip.Rect{Max: dims.Size}.Push(gtx.Ops)
+	pointer.CursorGrab.Add(gtx.Ops)
+	h.move.Add(gtx.Ops)
+	area.Pop()
+	call.Add(gtx.Ops)
+
+	// and its right edge to resize
+	handle := gtx.Dp(resizeHandle)
+	area = clip.Rect{Min: image.Point{X: width - handle}, Max: dims.Size}.Push(gtx.Ops)
+	pointer.CursorColResize.Add(gtx.Ops)
+	h.resize.Add(gtx.Ops)
+	area.Pop()
+
+	return dims
+}
+
+// layoutDetailsRow draws a single entry as a row of the details view.
+func (entries *Entries) layoutDetailsRow(gtx layout.Context, theme *material.Theme, e Entry, selected bool) layout.Dimensions {
+	var children []layout.FlexChild
+	for _, c := range entries.Details.visible() {
+		c := c
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints = layout.Exact(image.Point{X: gtx.Dp(c.Width), Y: gtx.Constraints.Max.Y})
+			gtx.Constraints.Min.Y = 0
+
+			return layout.Inset{Left: unit.Dp(6), Right: unit.Dp(6)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				if c.Column != ColumnName {
+					label := material.Body2(theme, c.Column.Value(e))
+					label.MaxLines = 1
+					if c.Column == ColumnSize {
+						label.Alignment = text.End
+					}
+					return label.Layout(gtx)
+				}
+
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return e.LayoutIcon(gtx, detailsIconSize)
+					}),
+					layout.Rigid(layout.Spacer{Width: unit.Dp(6)}.Layout),
+					layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+						label := material.Body2(theme, e.Name())
+						label.MaxLines = 1
+						return label.Layout(gtx)
+					}),
+				)
+			})
+		}))
+	}
+
+	return highlight(gtx, theme, selected, func(gtx layout.Context) layout.Dimensions {
+		return material.Clickable(gtx, e.Clickable, func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+			})
+		})
+	})
+}
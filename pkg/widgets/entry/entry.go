@@ -1,17 +1,19 @@
 package entry
 
 import (
+	"context"
 	"image"
-	"os"
+	"io/fs"
 	"path/filepath"
-	"sort"
 
 	"gioui.org/layout"
+	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/unit"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"github.com/ShakedGold/Gole/pkg/assets"
+	"github.com/ShakedGold/Gole/pkg/explorer/mimetype"
 	"github.com/ShakedGold/Gole/pkg/widgets"
 	"github.com/ShakedGold/Gole/pkg/widgets/grid"
 	"github.com/fsnotify/fsnotify"
@@ -23,6 +25,16 @@ const (
 	ViewModeList = iota
 	// ViewModeGrid is the grid view mode
 	ViewModeGrid
+	// ViewModeDetails is the details view mode
+	ViewModeDetails
+)
+
+// gridIconSize, listIconSize and detailsIconSize are how large the icons
+// are in each view.
+const (
+	gridIconSize    = unit.Dp(128)
+	listIconSize    = unit.Dp(26)
+	detailsIconSize = unit.Dp(19)
 )
 
 type Entry struct {
@@ -33,39 +45,104 @@ type Entry struct {
 	IsFolder  bool
 	Clickable *widget.Clickable
 	Icon      *image.Image
+	Info      fs.FileInfo
+	// Type is the detected type of the entry, set along with Info
+	Type mimetype.Type
+	// Hidden is set for entries listed in the .hidden file of their folder
+	Hidden bool
+	// thumbnail is shown in place of Icon once it is made. thumbnailed is
+	// set then, or once it could not be made.
+	thumbnail   *thumbnailImage
+	thumbnailed bool
 }
 
 type Entries struct {
-	Entries  []Entry
-	Path     string
-	Grid     *grid.Grid
-	List     *layout.List
-	ViewMode int
+	Entries   []Entry
+	Path      string
+	Grid      *grid.Grid
+	List      *layout.List
+	ViewMode  int
+	Selection *Selection
+	Details   *Details
+	Sorter    *Sorter
+	Filter    *Filter
+	// Virtual is set for entries that are not the content of the folder at
+	// Path, like search results
+	Virtual bool
+	// Invalidate, if set, is called when entries are added from another
+	// goroutine so a new frame shows them
+	Invalidate func()
+
+	// all is every entry of the folder, Entries only has the ones passing
+	// the filter
+	all      []Entry
+	pending  pending
+	marquee  marquee
+	keyboard keyboard
+	itemSize image.Point
+	viewport image.Point
+	// groups are the group headers of the entries, nil if not grouped
+	groups []Group
+	rows   []row
+	// rowLayout is what the rows were made for, they are made again when it
+	// changes or when the entries were prepared
+	rowLayout rowLayout
+	rowsStale bool
+	// anchor is the entry at the top of the view while the entries change
+	// under it, the view is scrolled back to it once the rows are known
+	anchor anchor
+	// scrollTarget is the entry ScrollTo scrolls to once the rows are made
+	scrollTarget string
+	// unread is set for entries of a folder that is loaded once switched to
+	unread bool
+	// thumbnails are the ones of the images in view in the grid
+	thumbnails thumbnails
 }
 
-func CreateFile(path string, alias string) (Entry, error) {
-	icon, err := assets.GetImage("file.png")
-	if err != nil {
-		return Entry{}, err
-	}
+// kindIcons are the icons of the files of each kind, file.png for the
+// others.
+var kindIcons = map[mimetype.Kind]string{
+	mimetype.KindDocument:   "document.png",
+	mimetype.KindCode:       "code.png",
+	mimetype.KindImage:      "image.png",
+	mimetype.KindAudio:      "audio.png",
+	mimetype.KindVideo:      "video.png",
+	mimetype.KindArchive:    "archive.png",
+	mimetype.KindExecutable: "executable.png",
+	mimetype.KindSymlink:    "symlink.png",
+}
 
-	return Entry{
+func CreateFile(path string, alias string) (Entry, error) {
+	entry := Entry{
 		Path:      path,
 		Alias:     alias,
 		IsFolder:  false,
 		Clickable: new(widget.Clickable),
-		Icon:      icon,
 		Width:     200,
 		Height:    200,
-	}, nil
+	}
+	entry.stat()
+
+	// the built in icon of the kind, shown when the icon theme has none
+	name, ok := kindIcons[entry.Type.Kind]
+	if !ok {
+		name = "file.png"
+	}
+	icon, err := assets.Icon(name)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Icon = icon
+
+	return entry, nil
 }
 func CreateFolder(path string, alias string) (Entry, error) {
-	icon, err := assets.GetImage("folder.png")
+	icon, err := assets.Icon("folder.png")
 	if err != nil {
 		return Entry{}, err
 	}
 
-	return Entry{
+	entry := Entry{
 		Path:      path,
 		Alias:     alias,
 		IsFolder:  true,
@@ -73,56 +150,61 @@ func CreateFolder(path string, alias string) (Entry, error) {
 		Icon:      icon,
 		Width:     200,
 		Height:    200,
-	}, nil
+	}
+	entry.stat()
+
+	return entry, nil
 }
 
+// ReadPath reads the folder at path right away, Open reads it in the
+// background instead.
 func ReadPath(path string) (*Entries, error) {
-	osEntries, err := os.ReadDir(path)
-
+	entrys := []Entry{}
+	err := readFolder(context.Background(), path, func(batch ...Entry) bool {
+		entrys = append(entrys, batch...)
+		return true
+	})
 	if err != nil {
 		return &Entries{}, err
 	}
 
+	return NewEntries(path, entrys), nil
+}
+
+// NewEntries creates entries for path that show the given entries.
+func NewEntries(path string, entrys []Entry) *Entries {
 	grid := widgets.Grid(9)
 	list := &layout.List{
 		Axis: layout.Vertical,
 	}
 
-	entries := Entries{
-		Entries: []Entry{},
-		Path:    path,
-		Grid:    &grid,
-		List:    list,
+	return &Entries{
+		Entries:   entrys,
+		all:       entrys,
+		pending:   pending{path: path},
+		Path:      path,
+		Grid:      &grid,
+		List:      list,
+		Selection: NewSelection(),
+		Details:   NewDetails(),
+		Sorter:    NewSorter(),
+		Filter:    NewFilter(),
 	}
+}
 
-	for _, osEntry := range osEntries {
-		var entry Entry
-		if osEntry.IsDir() {
-			entry, err = CreateFolder(filepath.Join(path, osEntry.Name()), osEntry.Name())
-			if err != nil {
-				return &Entries{}, err
-			}
-		} else {
-			entry, err = CreateFile(filepath.Join(path, osEntry.Name()), osEntry.Name())
-			if err != nil {
-				return &Entries{}, err
-			}
-		}
-
-		entries.Entries = append(entries.Entries, entry)
+// Name returns the name shown for the entry, the alias if it has one or
+// else the last part of its path.
+func (e Entry) Name() string {
+	if e.Alias != "" {
+		return e.Alias
 	}
-
-	return &entries, nil
+	return filepath.Base(e.Path)
 }
 
 func (e *Entry) Action(watcher *fsnotify.Watcher) (*Entries, error) {
 	if e.IsFolder {
-		entries, err := ReadPath(e.Path)
-		if err != nil {
-			return &Entries{}, err
-		}
-
-		entries, err = entries.Prepare()
+		// the folder is read in the background once switched to
+		entries, err := Open(e.Path)
 		if err != nil {
 			return &Entries{}, err
 		}
@@ -142,29 +224,66 @@ func (e *Entry) Action(watcher *fsnotify.Watcher) (*Entries, error) {
 }
 
 func (entries *Entries) Update(entrys *Entries) {
+	if entrys.Virtual {
+		entries.replaced("")
+	} else {
+		entries.replaced(entrys.Path)
+	}
 	entries.Path = entrys.Path
-	e, err := entrys.Prepare()
-	if err != nil {
-		return
+	entries.Virtual = entrys.Virtual
+	entries.all = entrys.all
+	if entries.all == nil {
+		entries.all = entrys.Entries
+	}
+	if entries.all == nil {
+		entries.all = []Entry{}
+	}
+	// keep the filter and order the user chose
+	entries.Prepare()
+
+	if entrys.unread {
+		entries.Load()
 	}
-	entries.Entries = e.Entries
 }
 
-func (entries *Entries) Prepare() (*Entries, error) {
-	// sort entries
-	sort.Slice(entries.Entries, ByIsDir(entries.Entries))
+// Selected returns the selected entries in the order they are shown.
+func (entries *Entries) Selected() []Entry {
+	var selected []Entry
+	for _, e := range entries.Entries {
+		if entries.Selection.IsSelected(e.Path) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
 
-	return entries, nil
+// SelectedPaths returns the paths of the selected entries in the order they
+// are shown.
+func (entries *Entries) SelectedPaths() []string {
+	return entries.Selection.Paths(entries.Entries)
 }
 
-func ByIsDir(entries []Entry) func(a, b int) bool {
-	return func(a, b int) bool {
-		// sort by IsDir, then by Name
-		if entries[a].IsFolder == entries[b].IsFolder {
-			return entries[a].Path < entries[b].Path
-		}
-		return entries[a].IsFolder
+func (entries *Entries) Prepare() (*Entries, error) {
+	if entries.all == nil {
+		entries.all = entries.Entries
+	}
+	if entries.Filter == nil {
+		entries.Filter = NewFilter()
 	}
+	if entries.Sorter == nil {
+		entries.Sorter = NewSorter()
+	}
+	if entries.Selection == nil {
+		entries.Selection = NewSelection()
+	}
+
+	entries.Entries = entries.Filter.Apply(entries.all)
+	entries.groups = entries.Sorter.Sort(entries.Entries)
+	entries.rowsStale = true
+	// entries filtered out can't stay selected
+	entries.Selection.Prune(entries.Entries)
+
+	return entries, nil
 }
 
 func (e Entry) GetGridLayout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
@@ -186,14 +305,7 @@ func (e Entry) GetGridLayout(gtx layout.Context, theme *material.Theme) layout.D
 						Alignment: layout.Middle,
 					}.Layout(gtx,
 						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							if e.Icon == nil {
-								return layout.Dimensions{}
-							}
-							icon := widget.Image{
-								Src: paint.NewImageOp(*e.Icon),
-							}
-
-							return icon.Layout(gtx)
+							return e.LayoutIcon(gtx, gridIconSize)
 						}),
 						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 							return layout.Inset{
@@ -203,15 +315,7 @@ func (e Entry) GetGridLayout(gtx layout.Context, theme *material.Theme) layout.D
 							})
 						}), // spacer
 						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							// get last part of path
-							var name string
-							if e.Alias != "" {
-								name = e.Alias
-							} else {
-								name = filepath.Base(e.Path)
-							}
-
-							return material.Body1(theme, name).Layout(gtx)
+							return material.Body1(theme, e.Name()).Layout(gtx)
 						}))
 				}),
 			)
@@ -235,29 +339,13 @@ func (e Entry) GetListLayout(gtx layout.Context, theme *material.Theme) layout.D
 						Spacing:   layout.SpaceBetween,
 					}.Layout(gtx,
 						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							if e.Icon == nil {
-								return layout.Dimensions{}
-							}
-							icon := widget.Image{
-								Src:   paint.NewImageOp(*e.Icon),
-								Scale: 0.2,
-							}
-
-							return icon.Layout(gtx)
+							return e.LayoutIcon(gtx, listIconSize)
 						}),
 						layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
 							return layout.Inset{
 								Left: unit.Dp(10),
 							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								// get last part of path
-								var name string
-								if e.Alias != "" {
-									name = e.Alias
-								} else {
-									name = filepath.Base(e.Path)
-								}
-
-								return material.Body1(theme, name).Layout(gtx)
+								return material.Body1(theme, e.Name()).Layout(gtx)
 							})
 						}))
 				}),
@@ -268,129 +356,191 @@ func (e Entry) GetListLayout(gtx layout.Context, theme *material.Theme) layout.D
 	return clickable
 }
 
-func (e Entry) Layout(gtx layout.Context, theme *material.Theme, viewMode int) layout.Dimensions {
+func (e Entry) Layout(gtx layout.Context, theme *material.Theme, viewMode int, selected bool) layout.Dimensions {
+	content := e.GetListLayout
 	if viewMode == ViewModeGrid {
-		return e.GetGridLayout(gtx, theme)
-	} else {
-		return e.GetListLayout(gtx, theme)
+		content = e.GetGridLayout
 	}
+
+	return highlight(gtx, theme, selected, func(gtx layout.Context) layout.Dimensions {
+		return content(gtx, theme)
+	})
 }
 
-func (entries *Entries) GetEntryLayout(index int, gtx layout.Context, theme *material.Theme, watcher *fsnotify.Watcher) (layout.Dimensions, *Entries, error) {
-	var updatedEntries *Entries
+// highlight draws a selected entry's background behind it.
+func highlight(gtx layout.Context, theme *material.Theme, selected bool, w layout.Widget) layout.Dimensions {
+	if !selected {
+		return w(gtx)
+	}
 
-	clickable := entries.Entries[index].Clickable
+	return layout.Background{}.Layout(gtx,
+		func(gtx layout.Context) layout.Dimensions {
+			defer clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(unit.Dp(4))).Push(gtx.Ops).Pop()
+			background := theme.Palette.ContrastBg
+			background.A = 0x40
+			paint.Fill(gtx.Ops, background)
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		},
+		w,
+	)
+}
 
-	if clickable == nil {
+// update handles the clicks on the entry at index. A single click selects
+// the entry and a double click opens it, the entries to switch to are
+// returned if a folder was opened.
+func (entries *Entries) update(gtx layout.Context, index int, watcher *fsnotify.Watcher) (*Entries, error) {
+	if entries.Entries[index].Clickable == nil {
 		entries.Entries[index].Clickable = new(widget.Clickable)
 	}
+	if entries.Selection == nil {
+		entries.Selection = NewSelection()
+	}
+
+	var updatedEntries *Entries
+	for {
+		click, ok := entries.Entries[index].Clickable.Update(gtx)
+		if !ok {
+			break
+		}
+
+		entries.keyboard.cursor = entries.Entries[index].Path
+		entries.Focus(gtx)
+
+		if !entries.Selection.Click(entries.Entries, index, click) {
+			continue
+		}
 
-	if clickable.Clicked(gtx) {
 		entry := entries.Entries[index]
 		newEntries, err := entry.Action(watcher)
 		if err != nil {
-			return layout.Dimensions{}, nil, err
+			return nil, err
 		}
 
-		// switch if new entries are available
-		if len(newEntries.Entries) > 0 {
+		// switch if a folder was opened
+		if newEntries.Path != "" {
 			updatedEntries = newEntries
 		}
 	}
 
-	return entries.Entries[index].Layout(gtx, theme, entries.ViewMode), updatedEntries, nil
-}
-
-func (entries *Entries) GetGridLayout(gtx layout.Context, theme *material.Theme, watcher *fsnotify.Watcher) (layout.Dimensions, *Entries, error) {
-	var layoutErr error
-	var updatedEntries *Entries
-
-	// get calculated width
-	width := gtx.Constraints.Max.X
-	// height := gtx.Constraints.Max.Y
-
-	if len(entries.Entries) == 0 {
-		return layout.Dimensions{}, nil, nil
-	}
-
-	// calculate number of columns
-	columns := width / entries.Entries[0].Width
-	if columns == 0 {
-		columns = 1
-	}
-
-	// update grid columns
-	entries.Grid.Columns = columns
-
-	layout := entries.Grid.Layout(gtx, len(entries.Entries), func(gtx layout.Context, index int) layout.Dimensions {
-		return entries.Entries[index].Layout(gtx, theme, entries.ViewMode)
-	})
-
-	return layout, updatedEntries, layoutErr
+	return updatedEntries, nil
 }
 
-func (entries *Entries) GetListLayout(gtx layout.Context, theme *material.Theme, watcher *fsnotify.Watcher) (layout.Dimensions, *Entries, error) {
-	var layoutErr error
-	var updatedEntries *Entries
-
-	layout := entries.List.Layout(gtx, len(entries.Entries), func(gtx layout.Context, index int) layout.Dimensions {
-		return entries.Entries[index].Layout(gtx, theme, entries.ViewMode)
-	})
-
-	return layout, updatedEntries, layoutErr
-}
-
-func (entries *Entries) getLayout() func(gtx layout.Context, len int, r layout.ListElement) layout.Dimensions {
+// getList returns the scrolling list of the current view.
+func (entries *Entries) getList() *layout.List {
 	if entries.ViewMode == ViewModeGrid {
-		return entries.Grid.Layout
-	} else {
-		return entries.List.Layout
+		return &entries.Grid.List.List
 	}
+	return entries.List
 }
 
 func (entries *Entries) Layout(gtx layout.Context, theme *material.Theme, watcher *fsnotify.Watcher) (layout.Dimensions, *Entries, error) {
 	var layoutErr error
 	var updatedEntries *Entries
 
-	if entries.ViewMode == ViewModeGrid {
+	// take in entries added since the last frame
+	entries.flush()
+	entries.flushThumbnails()
+
+	// empty folders are still laid out so they get keyboard events
+	if entries.ViewMode == ViewModeGrid && len(entries.Entries) > 0 {
 		// get calculated width
 		width := gtx.Constraints.Max.X
 		// height := gtx.Constraints.Max.Y
 
-		if len(entries.Entries) == 0 {
-			return layout.Dimensions{}, nil, nil
-		}
-
 		// calculate number of columns
 		columns := width / entries.Entries[0].Width
 		if columns == 0 {
 			columns = 1
 		}
 
+		// keep the entries in view when the number of columns changes
+		if columns != entries.Grid.Columns && entries.rowLayout.columns == entries.Grid.Columns {
+			entries.keepAnchor()
+		}
+
 		// update grid columns
 		entries.Grid.Columns = columns
 	}
 
-	layout := entries.getLayout()(gtx, len(entries.Entries), func(gtx layout.Context, index int) layout.Dimensions {
-		entry := entries.Entries[index]
+	if entries.Selection == nil {
+		entries.Selection = NewSelection()
+	}
+	if entries.Details == nil {
+		entries.Details = NewDetails()
+	}
+
+	body := func(gtx layout.Context) layout.Dimensions {
+		entries.viewport = gtx.Constraints.Max
+		entries.layoutRows(gtx)
+		entries.updateMarquee(gtx)
 
-		if entry.Clickable == nil {
-			entries.Entries[index].Clickable = new(widget.Clickable)
+		updatedEntries, layoutErr = entries.updateKeyboard(gtx, watcher)
+		if layoutErr != nil {
+			return layout.Dimensions{}
 		}
-		if entry.Clickable.Clicked(gtx) {
-			newEntries, err := entry.Action(watcher)
-			if err != nil {
-				layoutErr = err
-			}
 
-			// switch if new entries are available
-			if len(newEntries.Entries) > 0 {
-				updatedEntries = newEntries
+		dims := entries.layoutMarquee(gtx, func(gtx layout.Context) layout.Dimensions {
+			element := func(gtx layout.Context, index int) layout.Dimensions {
+				newEntries, err := entries.update(gtx, index, watcher)
+				if err != nil {
+					layoutErr = err
+				}
+				if newEntries != nil {
+					updatedEntries = newEntries
+				}
+
+				entry := entries.Entries[index]
+				if entries.ViewMode == ViewModeGrid {
+					entries.requestThumbnail(entry, gtx.Dp(gridIconSize))
+				}
+				selected := entries.Selection.IsSelected(entry.Path)
+				var dims layout.Dimensions
+				if entries.ViewMode == ViewModeDetails {
+					dims = entries.layoutDetailsRow(gtx, theme, entry, selected)
+				} else {
+					dims = entry.Layout(gtx, theme, entries.ViewMode, selected)
+				}
+				// every entry of a view has the same size, the marquee relies on it
+				entries.itemSize = dims.Size
+				entries.drawCursor(gtx, theme, entry, dims.Size)
+				return dims
 			}
-		}
 
-		return entry.Layout(gtx, theme, entries.ViewMode)
-	})
+			return entries.getList().Layout(gtx, len(entries.rows), func(gtx layout.Context, index int) layout.Dimensions {
+				r := entries.rows[index]
+				if r.isHeader() {
+					return layoutGroupHeader(gtx, theme, r.header)
+				}
+				if entries.ViewMode != ViewModeGrid {
+					return element(gtx, r.start)
+				}
+
+				return grid.Row(gtx, r.start, r.end, element)
+			})
+		})
+		entries.drawMarquee(gtx, theme)
+
+		return dims
+	}
+
+	var dims layout.Dimensions
+	if entries.ViewMode == ViewModeDetails {
+		// the column headers stay on top while the rows scroll
+		dims = layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return entries.layoutHeader(gtx, theme)
+			}),
+			layout.Flexed(1, body),
+		)
+	} else {
+		dims = body(gtx)
+	}
+	entries.layoutLoading(gtx, theme, dims.Size)
+	// the images scrolled out of view are not worth making anymore
+	entries.cancelThumbnails()
+	if layoutErr != nil {
+		return layout.Dimensions{}, nil, layoutErr
+	}
 
-	return layout, updatedEntries, layoutErr
+	return dims, updatedEntries, nil
 }
@@ -0,0 +1,211 @@
+package entry
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ShakedGold/Gole/pkg/explorer/mimetype"
+)
+
+// FileType is a kind of file the entries can be narrowed down to.
+type FileType int
+
+const (
+	TypeAny FileType = iota
+	TypeFolders
+	TypeFiles
+	TypeImages
+	TypeAudio
+	TypeVideo
+	TypeDocuments
+	TypeArchives
+	TypeCode
+)
+
+// FileTypes lists every file type in the order they are offered.
+var FileTypes = []FileType{
+	TypeAny,
+	TypeFolders,
+	TypeFiles,
+	TypeImages,
+	TypeAudio,
+	TypeVideo,
+	TypeDocuments,
+	TypeArchives,
+	TypeCode,
+}
+
+// typeKinds are the kinds of files of each file type.
+var typeKinds = map[FileType][]mimetype.Kind{
+	TypeImages:    {mimetype.KindImage},
+	TypeAudio:     {mimetype.KindAudio},
+	TypeVideo:     {mimetype.KindVideo},
+	TypeDocuments: {mimetype.KindDocument, mimetype.KindText},
+	TypeArchives:  {mimetype.KindArchive},
+	TypeCode:      {mimetype.KindCode},
+}
+
+// Title returns the name of the file type as shown to the user.
+func (t FileType) Title() string {
+	switch t {
+	case TypeFolders:
+		return "Folders"
+	case TypeFiles:
+		return "Files"
+	case TypeImages:
+		return "Images"
+	case TypeAudio:
+		return "Audio"
+	case TypeVideo:
+		return "Videos"
+	case TypeDocuments:
+		return "Documents"
+	case TypeArchives:
+		return "Archives"
+	case TypeCode:
+		return "Code"
+	default:
+		return "All types"
+	}
+}
+
+// Matches returns true if e is of the file type.
+func (t FileType) Matches(e Entry) bool {
+	switch t {
+	case TypeAny:
+		return true
+	case TypeFolders:
+		return e.IsFolder
+	case TypeFiles:
+		return !e.IsFolder
+	}
+
+	if e.IsFolder {
+		return false
+	}
+	for _, kind := range typeKinds[t] {
+		if e.Type.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter decides which entries of a folder are shown.
+type Filter struct {
+	// ShowHidden shows dotfiles and the files listed in .hidden
+	ShowHidden bool
+	// Pattern keeps the entries whose name matches it. It is a glob if it
+	// has any of *?[ in it, else the name only has to contain it. Case is
+	// ignored either way.
+	Pattern string
+	// Regex makes Pattern a regular expression
+	Regex bool
+	Type  FileType
+
+	// compiled is the pattern compiled is for, so the pattern is compiled
+	// once and not for every entry
+	compiled string
+	regex    *regexp.Regexp
+	err      error
+}
+
+// NewFilter creates a filter showing everything but hidden files.
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+// Err returns why the pattern is invalid, or nil. An invalid pattern is
+// ignored.
+func (f *Filter) Err() error {
+	f.compile()
+	return f.err
+}
+
+// Apply returns the entries of entrys that pass the filter.
+func (f *Filter) Apply(entrys []Entry) []Entry {
+	f.compile()
+
+	filtered := make([]Entry, 0, len(entrys))
+	for _, e := range entrys {
+		if f.Match(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// Match returns true if e passes the filter.
+func (f *Filter) Match(e Entry) bool {
+	if !f.ShowHidden && e.IsHidden() {
+		return false
+	}
+	if !f.Type.Matches(e) {
+		return false
+	}
+
+	f.compile()
+	if f.Pattern == "" || f.err != nil {
+		return true
+	}
+
+	name := e.Name()
+	switch {
+	case f.regex != nil:
+		return f.regex.MatchString(name)
+	case strings.ContainsAny(f.Pattern, "*?["):
+		matched, _ := filepath.Match(strings.ToLower(f.Pattern), strings.ToLower(name))
+		return matched
+	default:
+		return strings.Contains(strings.ToLower(name), strings.ToLower(f.Pattern))
+	}
+}
+
+// compile checks the pattern and compiles it if it is a regex.
+func (f *Filter) compile() {
+	key := f.Pattern
+	if f.Regex {
+		key = "regex:" + key
+	}
+	if key == f.compiled {
+		return
+	}
+	f.compiled = key
+	f.regex, f.err = nil, nil
+
+	switch {
+	case f.Pattern == "":
+	case f.Regex:
+		f.regex, f.err = regexp.Compile("(?i)" + f.Pattern)
+	default:
+		_, f.err = filepath.Match(f.Pattern, "")
+	}
+}
+
+// IsHidden returns true for dotfiles and the entries listed in the .hidden
+// file of their folder.
+func (e Entry) IsHidden() bool {
+	return e.Hidden || strings.HasPrefix(e.Name(), ".")
+}
+
+// readHidden returns the names listed in the .hidden file of folder.
+func readHidden(folder string) map[string]bool {
+	file, err := os.Open(filepath.Join(folder, ".hidden"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	hidden := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name != "" {
+			hidden[name] = true
+		}
+	}
+	return hidden
+}
@@ -0,0 +1,76 @@
+package entry
+
+import (
+	"image"
+	"math"
+	"sync"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"github.com/ShakedGold/Gole/pkg/assets"
+	"github.com/ShakedGold/Gole/pkg/explorer/mimetype"
+)
+
+// themeIconKey is the icon of a type of file in an icon theme, at a size and
+// scale.
+type themeIconKey struct {
+	theme  string
+	kind   mimetype.Type
+	folder bool
+	size   int
+	scale  int
+}
+
+// themeIcons keeps the icons of the icon theme found for each type of file,
+// nil for the types it has none for, so they are looked up once and not on
+// every frame.
+var themeIcons = struct {
+	mu    sync.Mutex
+	icons map[themeIconKey]*image.Image
+}{
+	icons: map[themeIconKey]*image.Image{},
+}
+
+// LayoutIcon draws the thumbnail of the entry if it has one, else the icon of
+// its type in the icon theme made for the size and scale it is shown at, else
+// its built in icon.
+func (e Entry) LayoutIcon(gtx layout.Context, size unit.Dp) layout.Dimensions {
+	if e.thumbnail != nil {
+		return assets.LayoutImageOp(gtx, e.thumbnail.thumbnails.imageOp(e.thumbnail.img, gtx.Dp(size)), size)
+	}
+	if icon := e.themeIcon(gtx, size); icon != nil {
+		return assets.LayoutIcon(gtx, icon, size)
+	}
+	return assets.LayoutIcon(gtx, e.Icon, size)
+}
+
+// themeIcon returns the icon of the type of the entry in the icon theme, made
+// for size at the scale of gtx, or nil if the theme has none.
+func (e Entry) themeIcon(gtx layout.Context, size unit.Dp) *image.Image {
+	// the icons of a theme come in whole scales, the next one up is scaled
+	// down sharper than the one below is scaled up
+	key := themeIconKey{
+		theme:  assets.IconTheme(),
+		kind:   e.Type,
+		folder: e.IsFolder,
+		size:   int(size),
+		scale:  max(int(math.Ceil(float64(gtx.Metric.PxPerDp))), 1),
+	}
+
+	themeIcons.mu.Lock()
+	defer themeIcons.mu.Unlock()
+	if icon, ok := themeIcons.icons[key]; ok {
+		return icon
+	}
+
+	names := e.Type.Icons()
+	if e.IsFolder {
+		names = []string{"folder"}
+	}
+	icon, err := assets.ThemeIcon(names, key.size, key.scale)
+	if err != nil {
+		icon = nil
+	}
+	themeIcons.icons[key] = icon
+	return icon
+}
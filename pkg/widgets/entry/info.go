@@ -0,0 +1,93 @@
+package entry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ShakedGold/Gole/pkg/explorer/mimetype"
+)
+
+// Size returns the size of the entry in bytes, 0 for folders.
+func (e Entry) Size() int64 {
+	if e.Info == nil || e.IsFolder {
+		return 0
+	}
+	return e.Info.Size()
+}
+
+// ModTime returns when the entry was last modified.
+func (e Entry) ModTime() time.Time {
+	if e.Info == nil {
+		return time.Time{}
+	}
+	return e.Info.ModTime()
+}
+
+// Permissions returns the permissions of the entry as shown by ls.
+func (e Entry) Permissions() string {
+	if e.Info == nil {
+		return ""
+	}
+	return e.Info.Mode().String()
+}
+
+// Owner returns the name of the user owning the entry.
+func (e Entry) Owner() string {
+	if e.Info == nil {
+		return ""
+	}
+	return owner(e.Info)
+}
+
+// TypeName returns a short description of the type of the entry, like
+// "PNG image".
+func (e Entry) TypeName() string {
+	if e.IsFolder {
+		return "Folder"
+	}
+	if e.Type.MIME != "" {
+		return e.Type.Comment()
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(e.Path), ".")
+	if ext == "" {
+		return "File"
+	}
+	return strings.ToUpper(ext) + " File"
+}
+
+// stat fills in the file info of the entry.
+func (e *Entry) stat() {
+	info, err := os.Lstat(e.Path)
+	if err == nil {
+		e.Info = info
+		e.Type = mimetype.Detect(e.Path, info)
+	}
+}
+
+// FormatSize returns size in a human readable form.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatTime returns t the way the details view shows it.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
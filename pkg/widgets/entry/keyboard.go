@@ -0,0 +1,306 @@
+package entry
+
+import (
+	"image"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/fsnotify/fsnotify"
+)
+
+// typeAheadTimeout is how long type-ahead find waits for the next letter
+// before starting a new search.
+const typeAheadTimeout = time.Second
+
+// keyboard is the keyboard navigation state of the entries.
+type keyboard struct {
+	// cursor is the path of the entry the keyboard is on
+	cursor string
+	// focused is true while the entries have the keyboard focus
+	focused bool
+
+	typed     string
+	lastTyped time.Time
+}
+
+var navigationKeys = []key.Name{
+	key.NameLeftArrow,
+	key.NameRightArrow,
+	key.NameUpArrow,
+	key.NameDownArrow,
+	key.NameHome,
+	key.NameEnd,
+	key.NamePageUp,
+	key.NamePageDown,
+	key.NameReturn,
+	key.NameEnter,
+	key.NameDeleteBackward,
+	key.NameSpace,
+}
+
+// Focus gives the keyboard focus to the entries.
+func (entries *Entries) Focus(gtx layout.Context) {
+	gtx.Execute(key.FocusCmd{Tag: entries})
+}
+
+// cursorIndex returns the index of the entry the keyboard is on, -1 if
+// there is none.
+func (entries *Entries) cursorIndex() int {
+	for i, e := range entries.Entries {
+		if e.Path == entries.keyboard.cursor {
+			return i
+		}
+	}
+	return -1
+}
+
+// columns returns how many entries are on a single row of the view.
+func (entries *Entries) columns() int {
+	if entries.ViewMode == ViewModeGrid && entries.Grid.Columns > 0 {
+		return entries.Grid.Columns
+	}
+	return 1
+}
+
+// updateKeyboard handles the keyboard events of the entries and returns the
+// entries to switch to if a folder was opened.
+func (entries *Entries) updateKeyboard(gtx layout.Context, watcher *fsnotify.Watcher) (*Entries, error) {
+	filters := []event.Filter{
+		key.FocusFilter{Target: entries},
+	}
+	for _, name := range navigationKeys {
+		filters = append(filters, key.Filter{
+			Focus:    entries,
+			Name:     name,
+			Optional: key.ModShift | key.ModShortcut,
+		})
+	}
+
+	var updatedEntries *Entries
+	for {
+		ev, ok := gtx.Event(filters...)
+		if !ok {
+			break
+		}
+
+		switch e := ev.(type) {
+		case key.FocusEvent:
+			entries.keyboard.focused = e.Focus
+		case key.EditEvent:
+			entries.typeAhead(gtx, e.Text)
+		case key.Event:
+			if e.State != key.Press {
+				continue
+			}
+
+			newEntries, err := entries.handleKey(gtx, e, watcher)
+			if err != nil {
+				return nil, err
+			}
+			if newEntries != nil {
+				updatedEntries = newEntries
+			}
+		}
+	}
+
+	return updatedEntries, nil
+}
+
+// handleKey moves the cursor or acts on the entry under it.
+func (entries *Entries) handleKey(gtx layout.Context, e key.Event, watcher *fsnotify.Watcher) (*Entries, error) {
+	if len(entries.Entries) == 0 && e.Name != key.NameDeleteBackward {
+		return nil, nil
+	}
+
+	current := entries.cursorIndex()
+	columns := entries.columns()
+	// rows that fit in the view, for page up and down
+	pageRows := entries.position().Count - 1
+	if pageRows < 1 {
+		pageRows = 1
+	}
+
+	target := current
+	switch e.Name {
+	case key.NameLeftArrow:
+		if columns > 1 {
+			target--
+		}
+	case key.NameRightArrow:
+		if columns > 1 {
+			target++
+		}
+	case key.NameUpArrow:
+		target = entries.rowStep(current, -1)
+	case key.NameDownArrow:
+		target = entries.rowStep(current, 1)
+	case key.NameHome:
+		target = 0
+	case key.NameEnd:
+		target = len(entries.Entries) - 1
+	case key.NamePageUp:
+		target = entries.rowStep(current, -pageRows)
+	case key.NamePageDown:
+		target = entries.rowStep(current, pageRows)
+	case key.NameSpace:
+		if current >= 0 && e.Modifiers.Contain(key.ModShortcut) {
+			entries.Selection.Toggle(entries.Entries[current].Path)
+		}
+		return nil, nil
+	case key.NameReturn, key.NameEnter:
+		if current < 0 {
+			return nil, nil
+		}
+		return entries.open(entries.Entries[current], watcher)
+	case key.NameDeleteBackward:
+		parent := filepath.Dir(entries.Path)
+		if parent == entries.Path {
+			return nil, nil
+		}
+		folder, err := CreateFolder(parent, "")
+		if err != nil {
+			return nil, err
+		}
+		return entries.open(folder, watcher)
+	}
+
+	// with no cursor yet, any movement starts at the first entry
+	if current < 0 {
+		target = 0
+	}
+	if target < 0 {
+		target = 0
+	}
+	if target >= len(entries.Entries) {
+		target = len(entries.Entries) - 1
+	}
+
+	entries.moveCursor(gtx, target, e.Modifiers)
+	return nil, nil
+}
+
+// moveCursor puts the cursor on the entry at index. Shift extends the
+// selection to it, ctrl moves without selecting.
+func (entries *Entries) moveCursor(gtx layout.Context, index int, modifiers key.Modifiers) {
+	path := entries.Entries[index].Path
+	entries.keyboard.cursor = path
+
+	switch {
+	case modifiers.Contain(key.ModShift):
+		entries.Selection.SelectRange(entries.Entries, path, modifiers.Contain(key.ModShortcut))
+	case modifiers.Contain(key.ModShortcut):
+	default:
+		entries.Selection.Select(path)
+	}
+
+	entries.scrollIntoView(index)
+}
+
+// open runs the action of e and returns the entries to switch to if it was
+// a folder.
+func (entries *Entries) open(e Entry, watcher *fsnotify.Watcher) (*Entries, error) {
+	newEntries, err := e.Action(watcher)
+	if err != nil {
+		return nil, err
+	}
+	if newEntries.Path == "" {
+		return nil, nil
+	}
+	return newEntries, nil
+}
+
+// typeAhead jumps to the first entry whose name starts with the letters
+// typed in quick succession.
+func (entries *Entries) typeAhead(gtx layout.Context, text string) {
+	kb := &entries.keyboard
+	if gtx.Now.Sub(kb.lastTyped) > typeAheadTimeout {
+		kb.typed = ""
+	}
+	kb.lastTyped = gtx.Now
+	kb.typed += strings.ToLower(text)
+
+	if len(entries.Entries) == 0 || kb.typed == "" {
+		return
+	}
+
+	// the same letter again cycles through the entries starting with it
+	start := entries.cursorIndex()
+	if len([]rune(kb.typed)) > 1 || start < 0 {
+		if start < 0 {
+			start = 0
+		}
+	} else {
+		start++
+	}
+
+	for i := 0; i < len(entries.Entries); i++ {
+		index := (start + i) % len(entries.Entries)
+		if strings.HasPrefix(strings.ToLower(entries.Entries[index].Name()), kb.typed) {
+			entries.moveCursor(gtx, index, 0)
+			return
+		}
+	}
+}
+
+// ScrollTo scrolls the view just enough for the entry at index to be fully
+// visible. It is done on the next frame, with the rows made for the number of
+// columns the view has then.
+func (entries *Entries) ScrollTo(index int) {
+	if index < 0 || index >= len(entries.Entries) {
+		return
+	}
+	entries.scrollTarget = entries.Entries[index].Path
+}
+
+// scrollIntoView scrolls the view just enough for the entry at index to be
+// fully visible. It returns false if the view has no size yet.
+func (entries *Entries) scrollIntoView(index int) bool {
+	rect := entries.itemRect(index, 0)
+	top, rowHeight := rect.Min.Y, rect.Dy()
+	if rowHeight <= 0 || entries.viewport.Y <= 0 {
+		return false
+	}
+
+	// show the header of the first group when going to the top
+	if i := entries.rowOf(index); i == 1 && entries.rows[0].isHeader() {
+		top = 0
+	}
+
+	offset := entries.scrollOffset()
+	switch {
+	case top < offset:
+		offset = top
+	case top+rowHeight > offset+entries.viewport.Y:
+		offset = top + rowHeight - entries.viewport.Y
+	default:
+		return true
+	}
+
+	position := entries.position()
+	position.First = entries.rowAt(offset)
+	if position.First < 0 {
+		position.First = 0
+	}
+	position.Offset = offset - entries.rows[position.First].top
+	return true
+}
+
+// drawCursor outlines the entry under the keyboard cursor.
+func (entries *Entries) drawCursor(gtx layout.Context, theme *material.Theme, e Entry, size image.Point) {
+	if !entries.keyboard.focused || e.Path != entries.keyboard.cursor {
+		return
+	}
+
+	paint.FillShape(gtx.Ops, theme.Palette.ContrastBg, clip.Stroke{
+		Path:  clip.UniformRRect(image.Rectangle{Max: size}, gtx.Dp(unit.Dp(4))).Path(gtx.Ops),
+		Width: float32(gtx.Dp(unit.Dp(1))),
+	}.Op())
+}
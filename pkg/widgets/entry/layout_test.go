@@ -0,0 +1,120 @@
+package entry
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// testEntries returns count prepared entries of 200 by 200 pixels in the
+// view mode.
+func testEntries(count int, mode int) *Entries {
+	entrys := make([]Entry, count)
+	for i := range entrys {
+		entrys[i] = Entry{
+			Path:      filepath.Join("/folder", fmt.Sprintf("file-%06d.txt", i)),
+			Clickable: new(widget.Clickable),
+			Width:     200,
+			Height:    200,
+		}
+	}
+	entries := NewEntries("/folder", entrys)
+	entries.Prepare()
+	entries.ViewMode = mode
+	return entries
+}
+
+// BenchmarkLayout shows that a frame costs the same whatever the number of
+// entries, as only the rows in view are laid out and the rows are not made
+// again while nothing changes.
+func BenchmarkLayout(b *testing.B) {
+	theme := material.NewTheme()
+	views := []struct {
+		name string
+		mode int
+	}{
+		{"list", ViewModeList},
+		{"grid", ViewModeGrid},
+		{"details", ViewModeDetails},
+	}
+
+	for _, view := range views {
+		for _, count := range []int{1000, 100000} {
+			b.Run(fmt.Sprintf("%s/%d", view.name, count), func(b *testing.B) {
+				entries := testEntries(count, view.mode)
+
+				var ops op.Ops
+				frame := func() {
+					ops.Reset()
+					gtx := layout.Context{
+						Ops:         &ops,
+						Constraints: layout.Exact(image.Pt(1800, 1000)),
+					}
+					if _, _, err := entries.Layout(gtx, theme, nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+				// the first frame makes the rows and scrolls to the middle
+				frame()
+				entries.position().First = len(entries.rows) / 2
+				frame()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					frame()
+				}
+			})
+		}
+	}
+}
+
+func TestScrollTo(t *testing.T) {
+	theme := material.NewTheme()
+	var ops op.Ops
+
+	for _, mode := range []int{ViewModeList, ViewModeGrid, ViewModeDetails} {
+		entries := testEntries(1000, mode)
+		frame := func(width int) {
+			ops.Reset()
+			gtx := layout.Context{
+				Ops:         &ops,
+				Constraints: layout.Exact(image.Pt(width, 1000)),
+			}
+			if _, _, err := entries.Layout(gtx, theme, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		visible := func(index int) bool {
+			rect := entries.itemRect(index, 0)
+			offset := entries.scrollOffset()
+			return rect.Dy() > 0 && rect.Min.Y >= offset && rect.Max.Y <= offset+entries.viewport.Y
+		}
+
+		// asked before the first frame, when nothing has a size yet
+		entries.ScrollTo(500)
+		frame(1800)
+		frame(1800)
+		if !visible(500) {
+			t.Errorf("mode %d: entry 500 is not in view", mode)
+		}
+
+		// back up, and with fewer columns at once in the grid
+		entries.ScrollTo(20)
+		frame(1000)
+		if !visible(20) {
+			t.Errorf("mode %d: entry 20 is not in view", mode)
+		}
+		entries.ScrollTo(900)
+		frame(600)
+		if !visible(900) {
+			t.Errorf("mode %d: entry 900 is not in view after the columns changed", mode)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package entry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+)
+
+// loadBatch is how many entries are read before they are handed over, so a
+// huge folder shows up while it is read.
+const loadBatch = 512
+
+// Open returns the entries of the folder at path without reading it. They
+// are read in the background once switched to with Update.
+func Open(path string) (*Entries, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", path)
+	}
+
+	entries := NewEntries(path, []Entry{})
+	entries.unread = true
+	return entries, nil
+}
+
+// Load reads the folder at Path again in the background, replacing the
+// entries with what is in it batch by batch. Switching to other entries
+// stops it, a load that was stopped never adds anything.
+func (entries *Entries) Load() {
+	entries.replaced(entries.Path)
+	entries.all = []Entry{}
+	entries.Prepare()
+
+	path := entries.Path
+	ctx, cancel := context.WithCancel(context.Background())
+	add := entries.Adder()
+
+	p := &entries.pending
+	p.mu.Lock()
+	generation := p.generation
+	p.cancel = cancel
+	p.loading = true
+	p.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := readFolder(ctx, path, add)
+
+		p.mu.Lock()
+		if p.generation != generation {
+			p.mu.Unlock()
+			return
+		}
+		missed := p.missed
+		p.loading, p.loadErr, p.cancel, p.missed = false, err, nil, false
+		p.mu.Unlock()
+
+		if missed {
+			entries.Reload()
+		}
+		if entries.Invalidate != nil {
+			entries.Invalidate()
+		}
+	}()
+}
+
+// Loading returns true while the folder is read in the background.
+func (entries *Entries) Loading() bool {
+	p := &entries.pending
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loading
+}
+
+// LoadErr returns why the folder could not be read completely the last time
+// it was loaded.
+func (entries *Entries) LoadErr() error {
+	p := &entries.pending
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loadErr
+}
+
+// readFolder reads the folder at path and passes its entries to add in
+// batches, until ctx is done or add returns false.
+func readFolder(ctx context.Context, path string, add func(entrys ...Entry) bool) error {
+	folder, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer folder.Close()
+
+	hidden := readHidden(path)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		osEntries, err := folder.ReadDir(loadBatch)
+		entrys := make([]Entry, 0, len(osEntries))
+		for _, osEntry := range osEntries {
+			var entry Entry
+			var createErr error
+			if osEntry.IsDir() {
+				entry, createErr = CreateFolder(filepath.Join(path, osEntry.Name()), osEntry.Name())
+			} else {
+				entry, createErr = CreateFile(filepath.Join(path, osEntry.Name()), osEntry.Name())
+			}
+			if createErr != nil {
+				return createErr
+			}
+
+			entry.Hidden = hidden[osEntry.Name()]
+			entrys = append(entrys, entry)
+		}
+
+		if len(entrys) > 0 && !add(entrys...) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// layoutLoading draws a spinner in the top right corner of a view of size
+// while the folder is loaded, and why it could not be read if it is empty.
+func (entries *Entries) layoutLoading(gtx layout.Context, theme *material.Theme, size image.Point) {
+	if err := entries.LoadErr(); err != nil && len(entries.Entries) == 0 {
+		layout.UniformInset(unit.Dp(16)).Layout(gtx, material.Body1(theme, err.Error()).Layout)
+		return
+	}
+	if !entries.Loading() {
+		return
+	}
+
+	side := gtx.Dp(unit.Dp(24))
+	margin := gtx.Dp(unit.Dp(8))
+	defer op.Offset(image.Pt(size.X-side-margin, margin)).Push(gtx.Ops).Pop()
+	gtx.Constraints = layout.Exact(image.Pt(side, side))
+	material.Loader(theme).Layout(gtx)
+}
@@ -0,0 +1,133 @@
+package entry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// largeFolder is how many files the generated folder has.
+const largeFolder = 5000
+
+// generate creates a folder with count empty files in it.
+func generate(t testing.TB, count int) string {
+	t.Helper()
+
+	folder := t.TempDir()
+	for i := 0; i < count; i++ {
+		if err := os.WriteFile(filepath.Join(folder, fmt.Sprintf("file-%06d.txt", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return folder
+}
+
+// wait takes in what is loaded like layout does until the load is done.
+func wait(t *testing.T, entries *Entries) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Minute)
+	for entries.Loading() {
+		if time.Now().After(deadline) {
+			t.Fatal("the folder was not loaded in time")
+		}
+		entries.flush()
+		time.Sleep(time.Millisecond)
+	}
+	entries.flush()
+}
+
+func TestLoadLargeFolder(t *testing.T) {
+	folder := generate(t, largeFolder)
+
+	entries := NewEntries(t.TempDir(), nil)
+	var invalidated atomic.Int32
+	entries.Invalidate = func() { invalidated.Add(1) }
+
+	opened, err := Open(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries.Update(opened)
+	if !entries.Loading() {
+		t.Fatal("the folder is not loading")
+	}
+	wait(t, entries)
+
+	if err := entries.LoadErr(); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries.Entries) != largeFolder {
+		t.Fatalf("loaded %d entries, want %d", len(entries.Entries), largeFolder)
+	}
+	// every batch asks for a frame
+	if batches := largeFolder / loadBatch; int(invalidated.Load()) < batches {
+		t.Fatalf("invalidated %d times, want at least %d", invalidated.Load(), batches)
+	}
+}
+
+func TestLoadStale(t *testing.T) {
+	large := generate(t, largeFolder)
+	small := generate(t, 3)
+
+	entries := NewEntries(t.TempDir(), nil)
+	opened, err := Open(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries.Update(opened)
+
+	// navigate elsewhere while the large folder is loaded
+	opened, err = Open(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries.Update(opened)
+	wait(t, entries)
+
+	// give the stale load a chance to add anything it still could
+	time.Sleep(100 * time.Millisecond)
+	entries.flush()
+
+	if entries.Path != small {
+		t.Fatalf("showing %s, want %s", entries.Path, small)
+	}
+	if len(entries.Entries) != 3 {
+		t.Fatalf("loaded %d entries, want 3", len(entries.Entries))
+	}
+	for _, e := range entries.Entries {
+		if filepath.Dir(e.Path) != small {
+			t.Fatalf("%s is from a stale load", e.Path)
+		}
+	}
+}
+
+func TestLoadCancel(t *testing.T) {
+	folder := generate(t, largeFolder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	read := 0
+	err := readFolder(ctx, folder, func(entrys ...Entry) bool {
+		read += len(entrys)
+		cancel()
+		return true
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if read != loadBatch {
+		t.Fatalf("read %d entries after cancelling, want a single batch of %d", read, loadBatch)
+	}
+}
+
+func TestLoadMissingFolder(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,269 @@
+package entry
+
+import (
+	"image"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+)
+
+// marquee is the rubber band rectangle dragged over empty space to select
+// every entry it crosses.
+type marquee struct {
+	active bool
+	// dragged is set once the pointer moved, a press and release in place
+	// only clears the selection
+	dragged bool
+	// start is where the drag began, in content coordinates so it stays put
+	// while the view scrolls
+	start image.Point
+	// pointer is the last pointer position, relative to the view
+	pointer image.Point
+	// base is the selection the marquee adds to when ctrl is held
+	base []string
+}
+
+// autoScrollEdge is how close to the edge of the view the pointer has to be
+// for the view to scroll while dragging.
+const autoScrollEdge = unit.Dp(32)
+
+// position returns the scroll position of the current view.
+func (entries *Entries) position() *layout.Position {
+	if entries.ViewMode == ViewModeGrid {
+		return &entries.Grid.List.Position
+	}
+	return &entries.List.Position
+}
+
+// itemBounds returns the size each entry takes up in the current view.
+func (entries *Entries) itemBounds() image.Point {
+	size := entries.itemSize
+	if entries.ViewMode == ViewModeGrid && len(entries.Entries) > 0 {
+		size = image.Point{X: entries.Entries[0].Width, Y: entries.Entries[0].Height}
+	}
+	return size
+}
+
+// scrollOffset returns how far the view is scrolled in pixels. Every row
+// knows where it is, so it can be computed from the position.
+func (entries *Entries) scrollOffset() int {
+	position := entries.position()
+	if position.First >= len(entries.rows) {
+		return position.Offset
+	}
+	return entries.rows[position.First].top + position.Offset
+}
+
+// itemRect returns the area the entry at index takes up, in content
+// coordinates.
+func (entries *Entries) itemRect(index int, width int) image.Rectangle {
+	i := entries.rowOf(index)
+	if i < 0 {
+		return image.Rectangle{}
+	}
+	r := entries.rows[i]
+
+	if entries.ViewMode == ViewModeGrid {
+		size := entries.itemBounds()
+		min := image.Point{X: (index - r.start) * size.X, Y: r.top}
+		return image.Rectangle{Min: min, Max: min.Add(size)}
+	}
+
+	return image.Rect(0, r.top, width, r.top+r.height)
+}
+
+// updateMarquee handles the pointer events of the marquee and selects the
+// entries it crosses.
+func (entries *Entries) updateMarquee(gtx layout.Context) {
+	m := &entries.marquee
+	width := gtx.Constraints.Max.X
+
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: m,
+			Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+		position := e.Position.Round()
+
+		switch e.Kind {
+		case pointer.Press:
+			if e.Buttons != pointer.ButtonPrimary {
+				continue
+			}
+			// presses on an entry belong to the entry
+			if entries.indexAt(position.Add(image.Point{Y: entries.scrollOffset()}), width) >= 0 {
+				continue
+			}
+
+			entries.Focus(gtx)
+			*m = marquee{
+				active:  true,
+				start:   position.Add(image.Point{Y: entries.scrollOffset()}),
+				pointer: position,
+			}
+			if e.Modifiers.Contain(key.ModShortcut) {
+				m.base = entries.SelectedPaths()
+			}
+		case pointer.Drag:
+			if !m.active {
+				continue
+			}
+			m.pointer = position
+			m.dragged = true
+		case pointer.Release, pointer.Cancel:
+			if !m.active {
+				continue
+			}
+			if !m.dragged && m.base == nil && e.Kind == pointer.Release {
+				entries.Selection.Clear()
+			}
+			m.active = false
+		}
+	}
+
+	if !m.active || !m.dragged {
+		return
+	}
+
+	entries.autoScroll(gtx)
+	entries.selectMarquee(width)
+}
+
+// autoScroll scrolls the view while the pointer is dragged past its edges.
+func (entries *Entries) autoScroll(gtx layout.Context) {
+	m := &entries.marquee
+	edge := gtx.Dp(autoScrollEdge)
+	height := gtx.Constraints.Max.Y
+
+	var delta int
+	switch {
+	case m.pointer.Y < edge:
+		delta = m.pointer.Y - edge
+	case m.pointer.Y > height-edge:
+		delta = m.pointer.Y - (height - edge)
+	default:
+		return
+	}
+
+	// scroll faster the further out the pointer is
+	delta /= 2
+	if delta == 0 {
+		return
+	}
+
+	position := entries.position()
+	if delta < 0 && position.First == 0 && position.Offset <= 0 {
+		return
+	}
+	if delta > 0 && !position.BeforeEnd {
+		return
+	}
+	position.Offset += delta
+
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// selectMarquee selects the entries crossed by the marquee.
+func (entries *Entries) selectMarquee(width int) {
+	m := &entries.marquee
+	rect := m.rect(entries.scrollOffset())
+
+	paths := append([]string{}, m.base...)
+	for _, r := range entries.rows {
+		if r.top >= rect.Max.Y || r.top+r.height <= rect.Min.Y {
+			continue
+		}
+		for i := r.start; i < r.end; i++ {
+			if entries.itemRect(i, width).Overlaps(rect) {
+				paths = append(paths, entries.Entries[i].Path)
+			}
+		}
+	}
+
+	entries.Selection.Set(paths)
+}
+
+// indexAt returns the index of the entry at point in content coordinates,
+// or -1 if there is none.
+func (entries *Entries) indexAt(point image.Point, width int) int {
+	size := entries.itemBounds()
+	if size.X <= 0 || size.Y <= 0 || point.Y < 0 || point.X < 0 {
+		return -1
+	}
+
+	i := entries.rowAt(point.Y)
+	if i < 0 || entries.rows[i].isHeader() {
+		return -1
+	}
+	r := entries.rows[i]
+
+	index := r.start
+	if entries.ViewMode == ViewModeGrid {
+		index += point.X / size.X
+	}
+
+	if index >= r.end {
+		return -1
+	}
+	if !point.In(entries.itemRect(index, width)) {
+		return -1
+	}
+	return index
+}
+
+// rect returns the marquee rectangle in content coordinates.
+func (m *marquee) rect(scrollOffset int) image.Rectangle {
+	end := m.pointer.Add(image.Point{Y: scrollOffset})
+	return image.Rectangle{Min: m.start, Max: end}.Canon()
+}
+
+// layoutMarquee registers the marquee for pointer events over the whole
+// view, children laid out by w still get their own events.
+func (entries *Entries) layoutMarquee(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	area := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	event.Op(gtx.Ops, &entries.marquee)
+	// the entries themselves are the target of keyboard events
+	event.Op(gtx.Ops, entries)
+	dims := w(gtx)
+	area.Pop()
+
+	return dims
+}
+
+// drawMarquee draws the translucent marquee rectangle on top of the view.
+func (entries *Entries) drawMarquee(gtx layout.Context, theme *material.Theme) {
+	m := &entries.marquee
+	if !m.active || !m.dragged {
+		return
+	}
+
+	scrollOffset := entries.scrollOffset()
+	rect := m.rect(scrollOffset).Sub(image.Point{Y: scrollOffset})
+	rect = rect.Intersect(image.Rectangle{Max: gtx.Constraints.Max})
+
+	fill := theme.Palette.ContrastBg
+	fill.A = 0x30
+	paint.FillShape(gtx.Ops, fill, clip.Rect(rect).Op())
+
+	border := theme.Palette.ContrastBg
+	border.A = 0xa0
+	paint.FillShape(gtx.Ops, border, clip.Stroke{
+		Path:  clip.RRect{Rect: rect}.Path(gtx.Ops),
+		Width: float32(gtx.Dp(unit.Dp(1))),
+	}.Op())
+}
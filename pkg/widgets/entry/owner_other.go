@@ -0,0 +1,10 @@
+//go:build !unix
+
+package entry
+
+import "io/fs"
+
+// owner is not supported here.
+func owner(info fs.FileInfo) string {
+	return ""
+}
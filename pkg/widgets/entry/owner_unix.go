@@ -0,0 +1,35 @@
+//go:build unix
+
+package entry
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// owners caches user names by uid, looking them up is slow.
+var owners sync.Map
+
+// owner returns the name of the user owning the file described by info.
+func owner(info fs.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	if name, ok := owners.Load(uid); ok {
+		return name.(string)
+	}
+
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	owners.Store(uid, name)
+
+	return name
+}
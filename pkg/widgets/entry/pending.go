@@ -0,0 +1,103 @@
+package entry
+
+import (
+	"context"
+	"sync"
+)
+
+// pending holds the entries added from other goroutines until the next frame
+// takes them in.
+type pending struct {
+	mu sync.Mutex
+	// generation changes every time the entries are replaced, adders of an
+	// older generation are stale
+	generation int
+	queue      []Entry
+
+	// path is the folder the entries are refreshed from, empty when they
+	// are not the content of a folder
+	path string
+	// changed holds the entries that changed on the disk by path, nil for
+	// the removed ones
+	changed map[string]*Entry
+	// reload is set when changed holds every entry of the folder
+	reload bool
+
+	// loading is set while the folder is read in the background, cancel
+	// stops reading it
+	loading bool
+	loadErr error
+	cancel  context.CancelFunc
+	// missed is set when the folder changed while it was loaded
+	missed bool
+}
+
+// Adder returns a function adding entries to the ones shown now. It can be
+// called from any goroutine, the entries show up the next time they are
+// laid out. Once the entries are replaced, by opening another folder for
+// instance, it drops what it is given and returns false.
+func (entries *Entries) Adder() func(entrys ...Entry) bool {
+	p := &entries.pending
+	p.mu.Lock()
+	generation := p.generation
+	p.mu.Unlock()
+
+	return func(entrys ...Entry) bool {
+		p.mu.Lock()
+		if p.generation != generation {
+			p.mu.Unlock()
+			return false
+		}
+		p.queue = append(p.queue, entrys...)
+		p.mu.Unlock()
+
+		if entries.Invalidate != nil {
+			entries.Invalidate()
+		}
+		return true
+	}
+}
+
+// replaced makes every adder stale, drops what they queued and stops
+// loading the folder. The entries are refreshed from path from now on, if it
+// is not empty.
+func (entries *Entries) replaced(path string) {
+	p := &entries.pending
+	p.mu.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.cancel = nil
+	p.loading = false
+	p.loadErr = nil
+	p.missed = false
+	p.generation++
+	p.queue = nil
+	p.path = path
+	p.changed = nil
+	p.reload = false
+	p.mu.Unlock()
+
+	entries.dropThumbnails()
+}
+
+// flush takes in the queued entries and changes and returns true if there
+// were any.
+func (entries *Entries) flush() bool {
+	p := &entries.pending
+	p.mu.Lock()
+	queue, changed, reload := p.queue, p.changed, p.reload
+	p.queue, p.changed, p.reload = nil, nil, false
+	p.mu.Unlock()
+
+	if len(queue) == 0 && changed == nil {
+		return false
+	}
+
+	entries.all = append(entries.all, queue...)
+	if changed != nil {
+		entries.apply(changed, reload)
+	}
+	entries.Prepare()
+	return true
+}
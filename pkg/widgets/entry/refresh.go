@@ -0,0 +1,183 @@
+package entry
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// anchor remembers which entry is at the top of the view.
+type anchor struct {
+	path string
+	// header is set when the header of the group of the entry is at the top
+	header bool
+}
+
+// Refresh reads the entries named names in the folder at path again, adding
+// the new ones, updating the changed ones and dropping the ones that are
+// gone. It can be called from any goroutine, the changes show up the next
+// time the entries are laid out, keeping the selection and the scroll
+// position. It returns false if the entries don't show the folder at path.
+func (entries *Entries) Refresh(path string, names []string) bool {
+	// the .hidden file can hide or show any entry of the folder
+	if slices.Contains(names, ".hidden") {
+		return entries.refresh(path, nil)
+	}
+	return entries.refresh(path, names)
+}
+
+// Reload reads the folder shown again from any goroutine, like Refresh
+// does for every entry. It is for when changes may have been missed.
+func (entries *Entries) Reload() bool {
+	p := &entries.pending
+	p.mu.Lock()
+	path := p.path
+	p.mu.Unlock()
+
+	return entries.refresh(path, nil)
+}
+
+// refresh queues the entries named names in the folder at path, or all of
+// them if names is nil.
+func (entries *Entries) refresh(path string, names []string) bool {
+	p := &entries.pending
+	p.mu.Lock()
+	generation, shown, loading := p.generation, p.path, p.loading
+	// a folder being loaded is read again once loaded
+	if loading && shown == path {
+		p.missed = true
+	}
+	p.mu.Unlock()
+
+	if shown == "" || shown != path || loading {
+		return false
+	}
+
+	reload := names == nil
+	if reload {
+		osEntries, err := os.ReadDir(path)
+		if err != nil {
+			return false
+		}
+		names = make([]string, 0, len(osEntries))
+		for _, osEntry := range osEntries {
+			names = append(names, osEntry.Name())
+		}
+	}
+
+	hidden := readHidden(path)
+	changed := make(map[string]*Entry, len(names))
+	for _, name := range names {
+		entryPath := filepath.Join(path, name)
+		info, err := os.Lstat(entryPath)
+		if err != nil {
+			changed[entryPath] = nil
+			continue
+		}
+
+		var entry Entry
+		if info.IsDir() {
+			entry, err = CreateFolder(entryPath, name)
+		} else {
+			entry, err = CreateFile(entryPath, name)
+		}
+		if err != nil {
+			continue
+		}
+		entry.Hidden = hidden[name]
+		changed[entryPath] = &entry
+	}
+
+	p.mu.Lock()
+	if p.generation != generation {
+		p.mu.Unlock()
+		return false
+	}
+	if reload || p.changed == nil {
+		p.changed = changed
+	} else {
+		for entryPath, entry := range changed {
+			p.changed[entryPath] = entry
+		}
+	}
+	p.reload = p.reload || reload
+	p.mu.Unlock()
+
+	if entries.Invalidate != nil {
+		entries.Invalidate()
+	}
+	return true
+}
+
+// apply puts changed in place of the entries with the same path. Every
+// other entry is dropped if reload is set.
+func (entries *Entries) apply(changed map[string]*Entry, reload bool) {
+	entries.keepAnchor()
+
+	all := make([]Entry, 0, len(entries.all)+len(changed))
+	for _, e := range entries.all {
+		c, ok := changed[e.Path]
+		switch {
+		case !ok && !reload:
+			all = append(all, e)
+		case ok && c != nil:
+			// a click in progress carries on
+			c.Clickable = e.Clickable
+			keepThumbnail(c, e)
+			all = append(all, *c)
+		}
+		delete(changed, e.Path)
+	}
+
+	// what is left is new
+	for _, c := range changed {
+		if c != nil {
+			all = append(all, *c)
+		}
+	}
+	entries.all = all
+}
+
+// keepAnchor remembers the entry at the top of the view before the entries
+// change.
+func (entries *Entries) keepAnchor() {
+	position := entries.position()
+	// a view at the very top stays there, showing what is added above
+	if position.First == 0 && position.Offset == 0 {
+		return
+	}
+	if position.First >= len(entries.rows) {
+		return
+	}
+
+	r := entries.rows[position.First]
+	if r.start < len(entries.Entries) {
+		entries.anchor = anchor{path: entries.Entries[r.start].Path, header: r.isHeader()}
+	}
+}
+
+// restoreAnchor scrolls the view back to the entry that was at its top
+// before the entries changed, wherever it is now.
+func (entries *Entries) restoreAnchor() {
+	if entries.anchor.path == "" {
+		return
+	}
+	a := entries.anchor
+	entries.anchor = anchor{}
+
+	index := slices.IndexFunc(entries.Entries, func(e Entry) bool {
+		return e.Path == a.path
+	})
+	if index < 0 {
+		return
+	}
+
+	current := entries.rowOf(index)
+	if current < 0 {
+		return
+	}
+	if a.header && current > 0 && entries.rows[current-1].isHeader() {
+		current--
+	}
+	entries.position().First = current
+}
@@ -0,0 +1,166 @@
+package entry
+
+import (
+	"image"
+	"slices"
+	"sort"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+)
+
+// groupHeaderHeight is the height of the header above each group.
+const groupHeaderHeight = unit.Dp(36)
+
+// row is a line of the view, either a group header or the entries from
+// start to end. Headers start and end where their group starts.
+type row struct {
+	header string
+	start  int
+	end    int
+	// top and height place the row in content coordinates
+	top    int
+	height int
+}
+
+// isHeader returns true if the row is a group header.
+func (r row) isHeader() bool {
+	return r.header != ""
+}
+
+// rowLayout is what the rows of the view depend on besides the entries.
+type rowLayout struct {
+	columns      int
+	headerHeight int
+	itemHeight   int
+}
+
+// layoutRows splits the entries into the rows of the view, a header for
+// each group followed by its entries. The rows are only made again when the
+// entries were prepared or the layout changed, not on every frame.
+func (entries *Entries) layoutRows(gtx layout.Context) {
+	current := rowLayout{
+		columns:      entries.columns(),
+		headerHeight: gtx.Dp(groupHeaderHeight),
+		itemHeight:   entries.itemBounds().Y,
+	}
+	if entries.rowsStale || current != entries.rowLayout {
+		entries.makeRows(current)
+	}
+
+	entries.restoreAnchor()
+
+	// the size of the entries is only known once one was laid out
+	if target := entries.scrollTarget; target != "" {
+		index := slices.IndexFunc(entries.Entries, func(e Entry) bool { return e.Path == target })
+		if index < 0 || entries.scrollIntoView(index) {
+			entries.scrollTarget = ""
+		}
+	}
+}
+
+// makeRows splits the entries into rows for l.
+func (entries *Entries) makeRows(l rowLayout) {
+	groups := entries.groups
+	if groups == nil {
+		groups = []Group{{Start: 0, End: len(entries.Entries)}}
+	}
+
+	entries.rows = entries.rows[:0]
+	entries.rowLayout = l
+	entries.rowsStale = false
+	top := 0
+	for _, g := range groups {
+		if g.Title != "" {
+			entries.rows = append(entries.rows, row{header: g.Title, start: g.Start, end: g.Start, top: top, height: l.headerHeight})
+			top += l.headerHeight
+		}
+		for start := g.Start; start < g.End; start += l.columns {
+			end := start + l.columns
+			if end > g.End {
+				end = g.End
+			}
+			entries.rows = append(entries.rows, row{start: start, end: end, top: top, height: l.itemHeight})
+			top += l.itemHeight
+		}
+	}
+}
+
+// rowOf returns the index of the row holding the entry at index, or -1.
+func (entries *Entries) rowOf(index int) int {
+	i := sort.Search(len(entries.rows), func(i int) bool {
+		return entries.rows[i].end > index
+	})
+	if i == len(entries.rows) || entries.rows[i].start > index {
+		return -1
+	}
+	return i
+}
+
+// rowAt returns the index of the row at y in content coordinates, or -1.
+func (entries *Entries) rowAt(y int) int {
+	i := sort.Search(len(entries.rows), func(i int) bool {
+		r := entries.rows[i]
+		return r.top+r.height > y
+	})
+	if i == len(entries.rows) || y < entries.rows[i].top {
+		return -1
+	}
+	return i
+}
+
+// rowStep returns the entry steps rows below the entry at index, in the same
+// column if that row is long enough. Negative steps go up.
+func (entries *Entries) rowStep(index int, steps int) int {
+	current := entries.rowOf(index)
+	if current < 0 {
+		return index
+	}
+	column := index - entries.rows[current].start
+
+	target := current
+	direction := 1
+	if steps < 0 {
+		direction, steps = -1, -steps
+	}
+	for i := current + direction; i >= 0 && i < len(entries.rows) && steps > 0; i += direction {
+		if entries.rows[i].isHeader() {
+			continue
+		}
+		target = i
+		steps--
+	}
+
+	r := entries.rows[target]
+	if r.start+column >= r.end {
+		return r.end - 1
+	}
+	return r.start + column
+}
+
+// layoutGroupHeader draws the header of a group.
+func layoutGroupHeader(gtx layout.Context, theme *material.Theme, title string) layout.Dimensions {
+	size := image.Point{X: gtx.Constraints.Max.X, Y: gtx.Dp(groupHeaderHeight)}
+	gtx.Constraints = layout.Exact(size)
+
+	layout.Inset{Left: unit.Dp(8), Top: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		label := material.Body1(theme, title)
+		label.Font.Weight = 600
+		label.Color = theme.Palette.ContrastBg
+		label.MaxLines = 1
+		return label.Layout(gtx)
+	})
+
+	// a line under the title
+	line := theme.Palette.ContrastBg
+	line.A = 0x60
+	paint.FillShape(gtx.Ops, line, clip.Rect{
+		Min: image.Point{X: gtx.Dp(unit.Dp(8)), Y: size.Y - gtx.Dp(unit.Dp(4)) - 1},
+		Max: image.Point{X: size.X - gtx.Dp(unit.Dp(8)), Y: size.Y - gtx.Dp(unit.Dp(4))},
+	}.Op())
+
+	return layout.Dimensions{Size: size}
+}
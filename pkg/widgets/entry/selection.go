@@ -0,0 +1,197 @@
+package entry
+
+import (
+	"gioui.org/io/key"
+	"gioui.org/widget"
+)
+
+// Selection keeps track of the selected entries. Entries are remembered by
+// path so the selection survives sorting and refreshing.
+type Selection struct {
+	// OnChange, if set, is called with the selected paths every time the
+	// selection changes
+	OnChange func(paths []string)
+
+	selected map[string]bool
+	// anchor is where shift click ranges start from
+	anchor string
+}
+
+// NewSelection creates an empty selection.
+func NewSelection() *Selection {
+	return &Selection{
+		selected: map[string]bool{},
+	}
+}
+
+// IsSelected returns true if the entry at path is selected.
+func (s *Selection) IsSelected(path string) bool {
+	return s.selected[path]
+}
+
+// Len returns the number of selected entries.
+func (s *Selection) Len() int {
+	return len(s.selected)
+}
+
+// Paths returns the selected paths in the order they appear in entrys.
+func (s *Selection) Paths(entrys []Entry) []string {
+	paths := make([]string, 0, len(s.selected))
+	for _, e := range entrys {
+		if s.selected[e.Path] {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths
+}
+
+// Select makes path the only selected entry.
+func (s *Selection) Select(path string) {
+	s.selected = map[string]bool{path: true}
+	s.anchor = path
+	s.changed()
+}
+
+// Toggle adds path to the selection or removes it.
+func (s *Selection) Toggle(path string) {
+	if s.selected[path] {
+		delete(s.selected, path)
+	} else {
+		s.selected[path] = true
+	}
+	s.anchor = path
+	s.changed()
+}
+
+// SelectRange selects every entry between the anchor and path. If add is
+// false the previous selection is dropped.
+func (s *Selection) SelectRange(entrys []Entry, path string, add bool) {
+	from, to := -1, -1
+	for i, e := range entrys {
+		if e.Path == s.anchor {
+			from = i
+		}
+		if e.Path == path {
+			to = i
+		}
+	}
+	if to == -1 {
+		return
+	}
+	// without an anchor the range is just the clicked entry
+	if from == -1 {
+		from = to
+		s.anchor = path
+	}
+	if from > to {
+		from, to = to, from
+	}
+
+	if !add {
+		s.selected = map[string]bool{}
+	}
+	for i := from; i <= to; i++ {
+		s.selected[entrys[i].Path] = true
+	}
+	s.changed()
+}
+
+// Set makes paths the selected entries.
+func (s *Selection) Set(paths []string) {
+	selected := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		selected[path] = true
+	}
+	if sameKeys(selected, s.selected) {
+		return
+	}
+
+	s.selected = selected
+	s.changed()
+}
+
+// SelectAll selects every entry.
+func (s *Selection) SelectAll(entrys []Entry) {
+	s.selected = make(map[string]bool, len(entrys))
+	for _, e := range entrys {
+		s.selected[e.Path] = true
+	}
+	s.changed()
+}
+
+// Clear unselects everything.
+func (s *Selection) Clear() {
+	if len(s.selected) == 0 {
+		return
+	}
+	s.selected = map[string]bool{}
+	s.anchor = ""
+	s.changed()
+}
+
+// Prune drops every selected path that is not in entrys anymore.
+func (s *Selection) Prune(entrys []Entry) {
+	present := make(map[string]bool, len(entrys))
+	for _, e := range entrys {
+		present[e.Path] = true
+	}
+
+	changed := false
+	for path := range s.selected {
+		if !present[path] {
+			delete(s.selected, path)
+			changed = true
+		}
+	}
+	if !present[s.anchor] {
+		s.anchor = ""
+	}
+
+	if changed {
+		s.changed()
+	}
+}
+
+// Click updates the selection for a click on the entry at index and returns
+// true if the entry should be opened.
+func (s *Selection) Click(entrys []Entry, index int, click widget.Click) bool {
+	path := entrys[index].Path
+
+	switch {
+	case click.Modifiers.Contain(key.ModShift):
+		s.SelectRange(entrys, path, click.Modifiers.Contain(key.ModShortcut))
+	case click.Modifiers.Contain(key.ModShortcut):
+		s.Toggle(path)
+	case click.NumClicks >= 2:
+		s.Select(path)
+		return true
+	default:
+		s.Select(path)
+	}
+
+	return false
+}
+
+func (s *Selection) changed() {
+	if s.OnChange == nil {
+		return
+	}
+
+	paths := make([]string, 0, len(s.selected))
+	for path := range s.selected {
+		paths = append(paths, path)
+	}
+	s.OnChange(paths)
+}
+
+func sameKeys(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if !b[key] {
+			return false
+		}
+	}
+	return true
+}
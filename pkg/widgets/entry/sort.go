@@ -0,0 +1,386 @@
+package entry
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// SortBy is what entries are ordered by.
+type SortBy int
+
+const (
+	SortByName SortBy = iota
+	SortBySize
+	SortByModified
+	SortByExtension
+	SortByType
+	SortByPermissions
+	SortByOwner
+)
+
+// Title returns the name of the sort order as shown to the user.
+func (by SortBy) Title() string {
+	switch by {
+	case SortBySize:
+		return "Size"
+	case SortByModified:
+		return "Modified"
+	case SortByExtension:
+		return "Extension"
+	case SortByType:
+		return "Type"
+	case SortByPermissions:
+		return "Permissions"
+	case SortByOwner:
+		return "Owner"
+	default:
+		return "Name"
+	}
+}
+
+// GroupBy is what entries are grouped by.
+type GroupBy int
+
+const (
+	GroupNone GroupBy = iota
+	GroupByType
+	GroupByDate
+	GroupBySize
+)
+
+// Title returns the name of the grouping as shown to the user.
+func (by GroupBy) Title() string {
+	switch by {
+	case GroupByType:
+		return "Type"
+	case GroupByDate:
+		return "Date"
+	case GroupBySize:
+		return "Size"
+	default:
+		return "None"
+	}
+}
+
+// Group is a run of entries sharing a group header.
+type Group struct {
+	Title string
+	// Start and End are the indices of the first entry of the group and the
+	// one after its last
+	Start, End int
+}
+
+// Sorter decides the order and grouping of entries.
+type Sorter struct {
+	By         SortBy
+	Descending bool
+	// FoldersFirst keeps folders before files whatever the order
+	FoldersFirst bool
+	// Natural compares the numbers in names by value, so "file2" comes
+	// before "file10"
+	Natural bool
+	// CaseSensitive orders "B" before "a"
+	CaseSensitive bool
+	// Locale collates names by the rules of a language, such as "de" or
+	// "sv-SE". Names are compared by code point if it is empty.
+	Locale string
+	Group  GroupBy
+}
+
+// NewSorter creates a sorter ordering by name, folders first, using the
+// locale of the user.
+func NewSorter() *Sorter {
+	return &Sorter{
+		By:           SortByName,
+		FoldersFirst: true,
+		Natural:      true,
+		Locale:       systemLocale(),
+	}
+}
+
+// Sort orders entrys and returns their groups, or nil if they are not
+// grouped.
+func (s *Sorter) Sort(entrys []Entry) []Group {
+	compare := s.compareNames()
+	now := time.Now()
+
+	type keyed struct {
+		entry Entry
+		rank  int
+		title string
+	}
+	items := make([]keyed, len(entrys))
+	for i, e := range entrys {
+		rank, title := s.group(e, now)
+		items[i] = keyed{entry: e, rank: rank, title: title}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.title != b.title {
+			return compare(a.title, b.title) < 0
+		}
+		return s.less(a.entry, b.entry, compare)
+	})
+
+	for i := range items {
+		entrys[i] = items[i].entry
+	}
+
+	if s.Group == GroupNone {
+		return nil
+	}
+
+	var groups []Group
+	for i, item := range items {
+		if len(groups) == 0 || groups[len(groups)-1].Title != item.title {
+			groups = append(groups, Group{Title: item.title, Start: i})
+		}
+		groups[len(groups)-1].End = i + 1
+	}
+	return groups
+}
+
+// SortByColumn orders by what column shows, clicking the same column again
+// reverses the order.
+func (s *Sorter) SortByColumn(column Column) {
+	by := column.SortBy()
+	if s.By == by {
+		s.Descending = !s.Descending
+		return
+	}
+	s.By = by
+	s.Descending = false
+}
+
+// less reports whether a comes before b within a group.
+func (s *Sorter) less(a, b Entry, compare func(a, b string) int) bool {
+	if s.FoldersFirst && a.IsFolder != b.IsFolder {
+		return a.IsFolder
+	}
+
+	var c int
+	switch s.By {
+	case SortBySize:
+		c = compareInt(a.Size(), b.Size())
+	case SortByModified:
+		c = a.ModTime().Compare(b.ModTime())
+	case SortByExtension:
+		c = compare(strings.TrimPrefix(filepath.Ext(a.Name()), "."), strings.TrimPrefix(filepath.Ext(b.Name()), "."))
+	case SortByType:
+		c = compare(a.TypeName(), b.TypeName())
+	case SortByPermissions:
+		c = strings.Compare(a.Permissions(), b.Permissions())
+	case SortByOwner:
+		c = compare(a.Owner(), b.Owner())
+	}
+	// ties are broken by name so the order is always the same
+	if c == 0 {
+		c = compare(a.Name(), b.Name())
+	}
+	if c == 0 {
+		c = strings.Compare(a.Path, b.Path)
+	}
+
+	if s.Descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+// compareNames returns the function comparing names with the options of the
+// sorter.
+func (s *Sorter) compareNames() func(a, b string) int {
+	if s.Locale != "" {
+		if tag, err := language.Parse(s.Locale); err == nil {
+			var options []collate.Option
+			if !s.CaseSensitive {
+				options = append(options, collate.IgnoreCase)
+			}
+			if s.Natural {
+				options = append(options, collate.Numeric)
+			}
+			collator := collate.New(tag, options...)
+			return collator.CompareString
+		}
+	}
+
+	return func(a, b string) int {
+		if !s.CaseSensitive {
+			a, b = strings.ToLower(a), strings.ToLower(b)
+		}
+		if s.Natural {
+			return compareNatural(a, b)
+		}
+		return strings.Compare(a, b)
+	}
+}
+
+// group returns the rank and title of the group e belongs to.
+func (s *Sorter) group(e Entry, now time.Time) (int, string) {
+	switch s.Group {
+	case GroupByType:
+		if e.IsFolder {
+			return 0, "Folders"
+		}
+		return 1, e.TypeName()
+	case GroupByDate:
+		return dateGroup(e.ModTime(), now)
+	case GroupBySize:
+		if e.IsFolder {
+			return 0, "Folders"
+		}
+		return sizeGroup(e.Size())
+	}
+	return 0, ""
+}
+
+// dateGroup returns the bucket t falls in, most recent first.
+func dateGroup(t time.Time, now time.Time) (int, string) {
+	if t.IsZero() {
+		return 9, "Unknown"
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// weeks start on monday
+	weekday := (int(today.Weekday()) + 6) % 7
+	thisWeek := today.AddDate(0, 0, -weekday)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	thisYear := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	switch {
+	case !t.Before(today.AddDate(0, 0, 1)):
+		return 0, "Future"
+	case !t.Before(today):
+		return 1, "Today"
+	case !t.Before(today.AddDate(0, 0, -1)):
+		return 2, "Yesterday"
+	case !t.Before(thisWeek):
+		return 3, "Earlier this week"
+	case !t.Before(thisWeek.AddDate(0, 0, -7)):
+		return 4, "Last week"
+	case !t.Before(thisMonth):
+		return 5, "Earlier this month"
+	case !t.Before(thisMonth.AddDate(0, -1, 0)):
+		return 6, "Last month"
+	case !t.Before(thisYear):
+		return 7, "Earlier this year"
+	default:
+		return 8, "A long time ago"
+	}
+}
+
+// sizeGroup returns the size range size falls in, smallest first.
+func sizeGroup(size int64) (int, string) {
+	const (
+		kb = 1024
+		mb = 1024 * kb
+		gb = 1024 * mb
+	)
+
+	switch {
+	case size == 0:
+		return 1, "Empty"
+	case size < 16*kb:
+		return 2, "Tiny (under 16 KB)"
+	case size < mb:
+		return 3, "Small (16 KB to 1 MB)"
+	case size < 128*mb:
+		return 4, "Medium (1 to 128 MB)"
+	case size < gb:
+		return 5, "Large (128 MB to 1 GB)"
+	default:
+		return 6, "Huge (over 1 GB)"
+	}
+}
+
+// compareNatural compares a and b treating runs of digits as numbers.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+
+		if isDigit(ra) && isDigit(rb) {
+			numberA, restA := digits(a)
+			numberB, restB := digits(b)
+
+			// without leading zeros the longer number is the larger one
+			trimmedA := strings.TrimLeft(numberA, "0")
+			trimmedB := strings.TrimLeft(numberB, "0")
+			if len(trimmedA) != len(trimmedB) {
+				return compareInt(int64(len(trimmedA)), int64(len(trimmedB)))
+			}
+			if c := strings.Compare(trimmedA, trimmedB); c != 0 {
+				return c
+			}
+			// equal values, fewer leading zeros first
+			if c := compareInt(int64(len(numberA)), int64(len(numberB))); c != 0 {
+				return c
+			}
+
+			a, b = restA, restB
+			continue
+		}
+
+		if ra != rb {
+			return compareInt(int64(ra), int64(rb))
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+
+	return compareInt(int64(len(a)), int64(len(b)))
+}
+
+// digits splits s after its leading ascii digits.
+func digits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(rune(s[i])) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(r rune) bool {
+	return r < utf8.RuneSelf && unicode.IsDigit(r)
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// systemLocale returns the collation locale of the user from the
+// environment, or an empty string if there is none.
+func systemLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		// "de_DE.UTF-8@euro" is "de-DE"
+		value, _, _ = strings.Cut(value, ".")
+		value, _, _ = strings.Cut(value, "@")
+		if value == "C" || value == "POSIX" {
+			return ""
+		}
+		return strings.ReplaceAll(value, "_", "-")
+	}
+	return ""
+}
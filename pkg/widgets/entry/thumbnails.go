@@ -0,0 +1,200 @@
+package entry
+
+import (
+	"image"
+	"sync"
+
+	"gioui.org/op/paint"
+	"github.com/ShakedGold/Gole/pkg/assets"
+	"github.com/ShakedGold/Gole/pkg/explorer/thumbnail"
+)
+
+// maxThumbnailOps is how many image ops of thumbnails are kept, more than
+// the grid shows at once. The ones used least recently are dropped first.
+const maxThumbnailOps = 256
+
+// thumbnails are the thumbnails of the image entries of the grid view. They
+// are made in the background for the entries in view, which show the icon
+// of their type until theirs is ready.
+type thumbnails struct {
+	// pool is made the first time a thumbnail is requested
+	pool *thumbnail.Pool
+
+	mu sync.Mutex
+	// ready holds the thumbnails made since the last frame by path, nil for
+	// the files that could not be read
+	ready map[string]*image.Image
+
+	// wanted are the entries requested in the frame being laid out, the
+	// requests for the others are cancelled once it is. It is only used
+	// from the goroutine laying out the entries.
+	wanted map[string]bool
+
+	// ops draw the thumbnails at the sizes they are shown at. They are kept
+	// here rather than with the icons so they go with the folder, and are
+	// only used from the goroutine laying out the entries.
+	ops map[thumbnailOp]paint.ImageOp
+	// used are the ops kept, the one used last last
+	used []thumbnailOp
+}
+
+// thumbnailImage is the thumbnail of an entry, drawn with the ops kept by
+// the thumbnails of its entries.
+type thumbnailImage struct {
+	img        *image.Image
+	thumbnails *thumbnails
+}
+
+// thumbnailOp is a thumbnail at a size in pixels.
+type thumbnailOp struct {
+	img  *image.Image
+	size int
+}
+
+// imageOp returns the op drawing img with its longest side size pixels
+// long, made the first time.
+func (t *thumbnails) imageOp(img *image.Image, size int) paint.ImageOp {
+	key := thumbnailOp{img: img, size: size}
+	if op, ok := t.ops[key]; ok {
+		t.use(key)
+		return op
+	}
+
+	if t.ops == nil {
+		t.ops = map[thumbnailOp]paint.ImageOp{}
+	}
+	if len(t.used) >= maxThumbnailOps {
+		delete(t.ops, t.used[0])
+		t.used = t.used[1:]
+	}
+	op := assets.NewImageOp(img, size)
+	t.ops[key] = op
+	t.used = append(t.used, key)
+	return op
+}
+
+// use moves key to the end of the used ops.
+func (t *thumbnails) use(key thumbnailOp) {
+	for i, used := range t.used {
+		if used == key {
+			copy(t.used[i:], t.used[i+1:])
+			t.used[len(t.used)-1] = key
+			return
+		}
+	}
+}
+
+// requestThumbnail asks for the thumbnail of e, size pixels large, if it is
+// an image that has none yet. It is called for the entries in view, every
+// frame.
+func (entries *Entries) requestThumbnail(e Entry, size int) {
+	if e.IsFolder || e.thumbnailed || !thumbnail.Supported(e.Type.MIME) {
+		return
+	}
+
+	t := &entries.thumbnails
+	if t.pool == nil {
+		t.pool = thumbnail.NewPool(0, entries.thumbnailDone)
+		// shared with the other applications, so folders seen before show
+		// their thumbnails right away
+		t.pool.Cache = thumbnail.DefaultCache()
+	}
+	if t.wanted == nil {
+		t.wanted = map[string]bool{}
+	}
+	t.wanted[e.Path] = true
+	t.pool.Request(e.Path, size)
+}
+
+// thumbnailDone keeps the thumbnail made for the file at path in a worker,
+// for the next frame to show it.
+func (entries *Entries) thumbnailDone(path string, img image.Image, err error) {
+	t := &entries.thumbnails
+	t.mu.Lock()
+	if t.ready == nil {
+		t.ready = map[string]*image.Image{}
+	}
+	if err != nil {
+		t.ready[path] = nil
+	} else {
+		t.ready[path] = &img
+	}
+	t.mu.Unlock()
+
+	if entries.Invalidate != nil {
+		entries.Invalidate()
+	}
+}
+
+// cancelThumbnails cancels the requests for the entries that were not laid
+// out in the last frame, as they are out of view.
+func (entries *Entries) cancelThumbnails() {
+	t := &entries.thumbnails
+	if t.pool == nil {
+		return
+	}
+	t.pool.Retain(func(path string) bool {
+		return t.wanted[path]
+	})
+	clear(t.wanted)
+}
+
+// flushThumbnails gives the thumbnails made since the last frame to their
+// entries.
+func (entries *Entries) flushThumbnails() {
+	t := &entries.thumbnails
+	t.mu.Lock()
+	ready := t.ready
+	t.ready = nil
+	t.mu.Unlock()
+
+	if len(ready) == 0 {
+		return
+	}
+
+	// the entries shown and every entry share the thumbnail of a file
+	images := make(map[string]*thumbnailImage, len(ready))
+	for path, img := range ready {
+		if img != nil {
+			images[path] = &thumbnailImage{img: img, thumbnails: t}
+		}
+	}
+
+	give := func(entrys []Entry) {
+		for i := range entrys {
+			if _, ok := ready[entrys[i].Path]; !ok {
+				continue
+			}
+			// a file that can't be read is not tried again
+			entrys[i].thumbnailed = true
+			entrys[i].thumbnail = images[entrys[i].Path]
+		}
+	}
+	give(entries.all)
+	give(entries.Entries)
+}
+
+// dropThumbnails cancels every request, drops the thumbnails not shown yet
+// and the ops of the ones shown, when the entries are replaced.
+func (entries *Entries) dropThumbnails() {
+	t := &entries.thumbnails
+	if t.pool != nil {
+		t.pool.CancelAll()
+	}
+	clear(t.ops)
+	t.used = nil
+
+	t.mu.Lock()
+	t.ready = nil
+	t.mu.Unlock()
+}
+
+// keepThumbnail gives c the thumbnail of e, the same entry before it was
+// read again, if the file did not change since.
+func keepThumbnail(c *Entry, e Entry) {
+	if !e.thumbnailed || c.Size() != e.Size() || !c.ModTime().Equal(e.ModTime()) {
+		return
+	}
+	c.thumbnail = e.thumbnail
+	c.thumbnailed = true
+}
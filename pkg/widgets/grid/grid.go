@@ -1,41 +1,35 @@
 package grid
 
 import (
+	"image"
+
 	"gioui.org/layout"
+	"gioui.org/op"
 	"gioui.org/widget"
 )
 
+// Grid holds the number of columns of a grid view and the list its rows
+// scroll in. The rows are made and laid out by the view, with Row.
 type Grid struct {
 	Columns int
 	List    *widget.List
 }
 
-func (g Grid) Layout(gtx layout.Context, len int, r layout.ListElement) layout.Dimensions {
-	// create a 2D array of rows/cols because we need to render it in a list so its scrollable
-	numberOfRows := len / g.Columns
-	remainder := len % g.Columns
-	if remainder > 0 {
-		numberOfRows++
-	}
+// Row lays out the items from start to end side by side like rigid children
+// of a horizontal flex, without allocating anything.
+func Row(gtx layout.Context, start, end int, r layout.ListElement) layout.Dimensions {
+	var size image.Point
+	for index := start; index < end; index++ {
+		cgtx := gtx
+		cgtx.Constraints.Min = image.Point{}
+		cgtx.Constraints.Max.X = max(gtx.Constraints.Max.X-size.X, 0)
 
-	rows := make([][]layout.FlexChild, numberOfRows)
-	for i := 0; i < numberOfRows; i++ {
-		rows[i] = make([]layout.FlexChild, g.Columns)
-		for j := 0; j < g.Columns; j++ {
-			index := i*g.Columns + j
-			rows[i][j] = layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-				if index >= len {
-					return layout.Dimensions{}
-				} else {
-					return r(gtx, index)
-				}
-			})
-		}
-	}
+		offset := op.Offset(image.Pt(size.X, 0)).Push(gtx.Ops)
+		dims := r(cgtx, index)
+		offset.Pop()
 
-	return g.List.Layout(gtx, numberOfRows, func(gtx layout.Context, index int) layout.Dimensions {
-		return layout.Flex{
-			Axis: layout.Horizontal,
-		}.Layout(gtx, rows[index]...)
-	})
+		size.X += dims.Size.X
+		size.Y = max(size.Y, dims.Size.Y)
+	}
+	return layout.Dimensions{Size: gtx.Constraints.Constrain(size)}
 }
@@ -0,0 +1,39 @@
+package grid
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// itemSize is the size of every item of the rows under test.
+const itemSize = 100
+
+func TestRow(t *testing.T) {
+	var ops op.Ops
+	gtx := layout.Context{
+		Ops:         &ops,
+		Constraints: layout.Constraints{Max: image.Pt(1000, 800)},
+	}
+
+	var laidOut []int
+	var widths []int
+	dims := Row(gtx, 10, 13, func(gtx layout.Context, index int) layout.Dimensions {
+		laidOut = append(laidOut, index)
+		widths = append(widths, gtx.Constraints.Max.X)
+		return layout.Dimensions{Size: image.Pt(itemSize, itemSize)}
+	})
+
+	if len(laidOut) != 3 || laidOut[0] != 10 || laidOut[2] != 12 {
+		t.Fatalf("laid out %v, want 10 to 12", laidOut)
+	}
+	// each item gets the room the ones before it left
+	if widths[0] != 1000 || widths[1] != 900 || widths[2] != 800 {
+		t.Fatalf("items got widths %v", widths)
+	}
+	if dims.Size != image.Pt(3*itemSize, itemSize) {
+		t.Fatalf("row is %v, want %v", dims.Size, image.Pt(3*itemSize, itemSize))
+	}
+}
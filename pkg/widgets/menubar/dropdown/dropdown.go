@@ -0,0 +1,127 @@
+package dropdown
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// DropdownMenuItem is a menu item that opens a list of choices below it.
+type DropdownMenuItem struct {
+	Clickable *widget.Clickable
+	Flexed    bool
+	// Items returns the choices to show when the dropdown is opened.
+	Items func() []string
+	// Selected returns the index of the highlighted choice, -1 for none.
+	Selected       func() int
+	OnSelect       func(gtx layout.Context, index int)
+	LayoutCallback func(gtx layout.Context, th *material.Theme) layout.Dimensions
+
+	state *state
+}
+
+type state struct {
+	open       bool
+	clickables []widget.Clickable
+	list       widget.List
+}
+
+func NewDropdownMenuItem(items func() []string, onSelect func(gtx layout.Context, index int), layoutCallback func(gtx layout.Context, th *material.Theme) layout.Dimensions) DropdownMenuItem {
+	return DropdownMenuItem{
+		Clickable:      new(widget.Clickable),
+		Items:          items,
+		OnSelect:       onSelect,
+		LayoutCallback: layoutCallback,
+		state: &state{
+			list: widget.List{
+				List: layout.List{Axis: layout.Vertical},
+			},
+		},
+	}
+}
+
+func (d DropdownMenuItem) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	for d.Clickable.Clicked(gtx) {
+		d.state.open = !d.state.open
+	}
+
+	dims := material.Clickable(gtx, d.Clickable, func(gtx layout.Context) layout.Dimensions {
+		return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return d.LayoutCallback(gtx, th)
+		})
+	})
+
+	if d.state.open && d.Items != nil {
+		d.layoutChoices(gtx, th, dims.Size.Y)
+	}
+
+	return dims
+}
+
+// layoutChoices draws the opened list on top of everything else, right below
+// the menu item.
+func (d DropdownMenuItem) layoutChoices(gtx layout.Context, th *material.Theme, top int) {
+	items := d.Items()
+	if len(d.state.clickables) < len(items) {
+		d.state.clickables = make([]widget.Clickable, len(items))
+	}
+
+	selected := -1
+	if d.Selected != nil {
+		selected = d.Selected()
+	}
+
+	for i := range items {
+		if d.state.clickables[i].Clicked(gtx) {
+			d.state.open = false
+			if d.OnSelect != nil {
+				d.OnSelect(gtx, i)
+			}
+			return
+		}
+	}
+
+	macro := op.Record(gtx.Ops)
+	op.Offset(image.Point{Y: top}).Add(gtx.Ops)
+
+	gtx.Constraints.Min = image.Point{}
+	gtx.Constraints.Max.X = gtx.Dp(unit.Dp(400))
+	gtx.Constraints.Max.Y = gtx.Dp(unit.Dp(300))
+
+	layout.Background{}.Layout(gtx,
+		func(gtx layout.Context) layout.Dimensions {
+			defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+			paint.Fill(gtx.Ops, th.Palette.Bg)
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		},
+		func(gtx layout.Context) layout.Dimensions {
+			return material.List(th, &d.state.list).Layout(gtx, len(items), func(gtx layout.Context, index int) layout.Dimensions {
+				return material.Clickable(gtx, &d.state.clickables[index], func(gtx layout.Context) layout.Dimensions {
+					return layout.UniformInset(unit.Dp(6)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						label := material.Body1(th, items[index])
+						if index == selected {
+							label.Color = th.Palette.ContrastBg
+						} else {
+							label.Color = color.NRGBA{A: 0xff}
+						}
+						label.MaxLines = 1
+						return label.Layout(gtx)
+					})
+				})
+			})
+		},
+	)
+
+	op.Defer(gtx.Ops, macro.Stop())
+}
+
+func (d DropdownMenuItem) IsFlexed() bool {
+	return d.Flexed
+}
@@ -2,6 +2,7 @@ package editor
 
 import (
 	"gioui.org/layout"
+	"gioui.org/unit"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 )
@@ -10,13 +11,21 @@ type EditorInputItem struct {
 	Editor         *widget.Editor
 	Flexed         bool
 	UpdateCallback func(*widget.Editor)
+	// Hint is shown while the editor is empty
+	Hint string
+	// Width, if set, is the width of an editor that is not flexed
+	Width unit.Dp
 }
 
 func (e EditorInputItem) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
 	if e.UpdateCallback != nil {
 		e.UpdateCallback(e.Editor)
 	}
-	return material.Editor(th, e.Editor, "Path").Layout(gtx)
+	if width := gtx.Dp(e.Width); width > 0 && width < gtx.Constraints.Max.X {
+		gtx.Constraints.Max.X = width
+		gtx.Constraints.Min.X = width
+	}
+	return material.Editor(th, e.Editor, e.Hint).Layout(gtx)
 }
 
 func (e EditorInputItem) IsFlexed() bool {
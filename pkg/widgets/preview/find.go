@@ -0,0 +1,65 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+// findChunkSize is how much of a file is searched at a time.
+const findChunkSize = 1 << 20
+
+var errOddPattern = errors.New("a hex pattern needs two digits for every byte")
+
+// parseHex returns the bytes of a hex pattern like "de ad be ef" or
+// "0xDEADBEEF".
+func parseHex(pattern string) ([]byte, error) {
+	pattern = strings.Join(strings.Fields(pattern), "")
+	pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, "0x"), "0X")
+	if len(pattern)%2 != 0 {
+		return nil, errOddPattern
+	}
+	return hex.DecodeString(pattern)
+}
+
+// find returns the offset of the first match of pattern in the size bytes of
+// r starting after from, going on from the start of r past its end, or -1 if
+// there is none.
+func find(ctx context.Context, r io.ReaderAt, size int64, pattern []byte, from int64) (int64, error) {
+	if len(pattern) == 0 || int64(len(pattern)) > size {
+		return -1, nil
+	}
+
+	from = min(max(from, 0), size)
+	at, err := findIn(ctx, r, size, pattern, from, size)
+	if at >= 0 || err != nil {
+		return at, err
+	}
+	return findIn(ctx, r, size, pattern, 0, from)
+}
+
+// findIn returns the offset of the first match of pattern starting between
+// start and end in the size bytes of r, or -1 if there is none.
+func findIn(ctx context.Context, r io.ReaderAt, size int64, pattern []byte, start, end int64) (int64, error) {
+	// chunks overlap by the length of pattern, for the matches across two
+	overlap := int64(len(pattern) - 1)
+	buf := make([]byte, findChunkSize+overlap)
+
+	for off := start; off < end; off += findChunkSize {
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-off)], off)
+		if err != nil && err != io.EOF {
+			return -1, err
+		}
+		if i := bytes.Index(buf[:n], pattern); i >= 0 && off+int64(i) < end {
+			return off + int64(i), nil
+		}
+	}
+	return -1, nil
+}
@@ -0,0 +1,610 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gioui.org/f32"
+	"gioui.org/io/clipboard"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/ShakedGold/Gole/pkg/widgets/entry"
+)
+
+const (
+	// hexTextSize is the size of the bytes, small for a row of them to fit
+	// in the pane
+	hexTextSize = unit.Sp(12)
+	// maxColumns is the most bytes on a row, fewer are when the pane is
+	// narrow
+	maxColumns = 16
+	// maxCopySize is the most bytes copied as hex at once
+	maxCopySize = 1 << 20
+)
+
+// hexView shows the bytes of a binary file in hex and as ASCII, reading only
+// the ones in view. Bytes are selected with the pointer and copied as hex,
+// and the file is searched for hex patterns or text.
+type hexView struct {
+	pager *pager
+	// invalidate, if set, asks for a new frame when a search ends
+	invalidate func()
+
+	list widget.List
+	// anchor is the byte the selection started at and cursor the one it
+	// ends at, both selected. cursor is -1 when nothing is.
+	anchor, cursor int64
+	selecting      bool
+
+	offsetEditor widget.Editor
+	findEditor   widget.Editor
+	// findText is set to search for text instead of hex patterns
+	findText                           bool
+	modeButton, nextButton, copyButton widget.Clickable
+	// message tells how the last search or copy went
+	message string
+
+	// columns is the number of bytes on a row, digits of the offsets and
+	// the size of a character of the last frame
+	columns   int
+	digits    int
+	charWidth float32
+	rowHeight int
+
+	mu sync.Mutex
+	// generation changes with every search, older results are dropped
+	generation int
+	cancel     context.CancelFunc
+	found      *found
+}
+
+// found is the result of a search.
+type found struct {
+	generation int
+	at         int64
+	length     int
+	err        error
+}
+
+// init sets up the editors, once.
+func (v *hexView) init() {
+	v.list.Axis = layout.Vertical
+	v.offsetEditor.SingleLine = true
+	v.offsetEditor.Submit = true
+	v.findEditor.SingleLine = true
+	v.findEditor.Submit = true
+	v.cancel = func() {}
+	v.cursor = -1
+}
+
+// set shows the file read by pager, closing the one shown before.
+// invalidate is called when a search ends.
+func (v *hexView) set(p *pager, invalidate func()) {
+	v.mu.Lock()
+	v.cancel()
+	v.generation++
+	v.found = nil
+	v.mu.Unlock()
+
+	if v.pager != nil {
+		v.pager.Close()
+	}
+	v.pager = p
+	v.invalidate = invalidate
+	v.anchor, v.cursor = 0, -1
+	v.selecting = false
+	v.message = ""
+	v.list.Position = layout.Position{}
+}
+
+// size returns the size of the file shown.
+func (v *hexView) size() int64 {
+	if v.pager == nil {
+		return 0
+	}
+	return v.pager.Size()
+}
+
+// selection returns the first and last bytes selected, if any are.
+func (v *hexView) selection() (int64, int64, bool) {
+	if v.cursor < 0 {
+		return 0, 0, false
+	}
+	return min(v.anchor, v.cursor), max(v.anchor, v.cursor), true
+}
+
+// selectRange selects the bytes from first to last and scrolls to them.
+func (v *hexView) selectRange(first, last int64) {
+	v.anchor, v.cursor = first, last
+	v.scrollTo(first)
+}
+
+// scrollTo scrolls the row of the byte at off into the middle of the view,
+// if it is out of it.
+func (v *hexView) scrollTo(off int64) {
+	columns := v.columns
+	if columns == 0 {
+		columns = maxColumns
+	}
+	row := int(off / int64(columns))
+	first, count := v.list.Position.First, v.list.Position.Count
+	if row >= first && row < first+count-1 {
+		return
+	}
+	v.list.Position = layout.Position{First: max(row-count/2, 0)}
+}
+
+// update handles the editors, the buttons, the pointer and the keys, and
+// takes in the result of the last search.
+func (v *hexView) update(gtx layout.Context) {
+	v.flushFound()
+
+	for {
+		ev, ok := v.offsetEditor.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.SubmitEvent); ok {
+			v.goTo(v.offsetEditor.Text())
+		}
+	}
+	for {
+		ev, ok := v.findEditor.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.SubmitEvent); ok {
+			v.findNext()
+		}
+	}
+	if v.nextButton.Clicked(gtx) {
+		v.findNext()
+	}
+	if v.modeButton.Clicked(gtx) {
+		v.findText = !v.findText
+	}
+	if v.copyButton.Clicked(gtx) {
+		v.copy(gtx)
+	}
+
+	for {
+		ev, ok := gtx.Event(
+			pointer.Filter{
+				Target: v,
+				Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+			},
+			key.Filter{Focus: v, Name: "C", Required: key.ModShortcut},
+		)
+		if !ok {
+			break
+		}
+
+		switch e := ev.(type) {
+		case pointer.Event:
+			at := v.byteAt(e.Position)
+			switch e.Kind {
+			case pointer.Press:
+				gtx.Execute(key.FocusCmd{Tag: v})
+				if at < 0 || e.Buttons != pointer.ButtonPrimary {
+					continue
+				}
+				// shift extends the selection
+				if !e.Modifiers.Contain(key.ModShift) || v.cursor < 0 {
+					v.anchor = at
+				}
+				v.cursor = at
+				v.selecting = true
+			case pointer.Drag:
+				if v.selecting && at >= 0 {
+					v.cursor = at
+				}
+			case pointer.Release, pointer.Cancel:
+				v.selecting = false
+			}
+		case key.Event:
+			if e.State == key.Press {
+				v.copy(gtx)
+			}
+		}
+	}
+}
+
+// byteAt returns the offset of the byte at pos in the view, the closest one
+// on its row if pos is between the bytes, or -1 if there is none.
+func (v *hexView) byteAt(pos f32.Point) int64 {
+	size := v.size()
+	if size == 0 || v.charWidth == 0 || v.rowHeight == 0 || v.columns == 0 {
+		return -1
+	}
+
+	y := max(v.list.Position.Offset+int(pos.Y), 0)
+	row := int64(v.list.Position.First + y/v.rowHeight)
+	column := int(pos.X / v.charWidth)
+
+	half := v.columns / 2
+	i := 0
+	switch hexStart, asciiStart := v.hexStart(), v.asciiStart(); {
+	case column >= asciiStart:
+		i = column - asciiStart
+	case column >= hexStart:
+		// the gap between the halves of the row
+		rel := column - hexStart
+		if rel > 3*half {
+			rel--
+		}
+		i = rel / 3
+	}
+	i = min(max(i, 0), v.columns-1)
+
+	return min(row*int64(v.columns)+int64(i), size-1)
+}
+
+// hexStart returns the character of a row the bytes in hex start at, after
+// the offset.
+func (v *hexView) hexStart() int {
+	return v.digits + 2
+}
+
+// hexColumn returns the character of a row the hex of byte i of the row
+// starts at, the second half of the bytes after a gap.
+func (v *hexView) hexColumn(i int) int {
+	column := v.hexStart() + 3*i
+	if i >= v.columns/2 {
+		column++
+	}
+	return column
+}
+
+// asciiStart returns the character of a row the bytes as ASCII start at.
+func (v *hexView) asciiStart() int {
+	return v.hexStart() + 3*v.columns + 2
+}
+
+// goTo selects the byte at the offset in text, in hex with 0x in front or
+// else decimal.
+func (v *hexView) goTo(text string) {
+	text = strings.TrimSpace(text)
+	var off int64
+	var err error
+	if rest, ok := strings.CutPrefix(strings.ToLower(text), "0x"); ok {
+		off, err = strconv.ParseInt(rest, 16, 64)
+	} else {
+		off, err = strconv.ParseInt(text, 10, 64)
+	}
+
+	switch {
+	case err != nil:
+		v.message = fmt.Sprintf("%q is not an offset", text)
+	case off < 0 || off >= v.size():
+		v.message = fmt.Sprintf("0x%X is past the end of the file", off)
+	default:
+		v.message = ""
+		v.selectRange(off, off)
+	}
+}
+
+// findNext starts searching for the pattern of the find editor after the
+// selection, from the start of the file past its end.
+func (v *hexView) findNext() {
+	if v.pager == nil {
+		return
+	}
+
+	var pattern []byte
+	if v.findText {
+		pattern = []byte(v.findEditor.Text())
+	} else {
+		var err error
+		pattern, err = parseHex(v.findEditor.Text())
+		if err != nil {
+			v.message = err.Error()
+			return
+		}
+	}
+	if len(pattern) == 0 {
+		return
+	}
+
+	from := int64(0)
+	if first, _, ok := v.selection(); ok {
+		from = first + 1
+	}
+
+	v.mu.Lock()
+	v.cancel()
+	v.generation++
+	generation := v.generation
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancel = cancel
+	v.mu.Unlock()
+
+	v.message = "Searching…"
+
+	// the file itself is read, the pages kept are the ones in view
+	file, size := v.pager.file, v.pager.Size()
+	go func() {
+		at, err := find(ctx, file, size, pattern, from)
+
+		v.mu.Lock()
+		if v.generation != generation {
+			v.mu.Unlock()
+			return
+		}
+		v.found = &found{generation: generation, at: at, length: len(pattern), err: err}
+		v.mu.Unlock()
+
+		if v.invalidate != nil {
+			v.invalidate()
+		}
+	}()
+}
+
+// flushFound selects the match of the search that ended since the last
+// frame.
+func (v *hexView) flushFound() {
+	v.mu.Lock()
+	f := v.found
+	v.found = nil
+	v.mu.Unlock()
+
+	if f == nil {
+		return
+	}
+
+	switch {
+	case f.err != nil:
+		v.message = f.err.Error()
+	case f.at < 0:
+		v.message = "Not found"
+	default:
+		v.message = fmt.Sprintf("Found at 0x%X", f.at)
+		v.selectRange(f.at, f.at+int64(f.length)-1)
+	}
+}
+
+// copy copies the selected bytes to the clipboard in hex.
+func (v *hexView) copy(gtx layout.Context) {
+	first, last, ok := v.selection()
+	if !ok || v.pager == nil {
+		return
+	}
+	if last-first+1 > maxCopySize {
+		v.message = fmt.Sprintf("Select at most %s to copy", entry.FormatSize(maxCopySize))
+		return
+	}
+
+	buf := make([]byte, last-first+1)
+	n, err := v.pager.ReadAt(buf, first)
+	if err != nil && err != io.EOF {
+		v.message = err.Error()
+		return
+	}
+
+	text := formatHex(buf[:n])
+	gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+	v.message = fmt.Sprintf("Copied %d bytes", n)
+}
+
+// formatHex returns b in hex, the bytes apart like they are shown.
+func formatHex(b []byte) string {
+	var text strings.Builder
+	for i, c := range b {
+		if i > 0 {
+			text.WriteByte(' ')
+		}
+		fmt.Fprintf(&text, "%02X", c)
+	}
+	return text.String()
+}
+
+// Layout draws the tools above the rows of bytes and the selection under
+// them.
+func (v *hexView) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	v.update(gtx)
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return v.layoutTools(gtx, theme)
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return v.layoutRows(gtx, theme)
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return v.layoutStatus(gtx, theme)
+		}),
+	)
+}
+
+// layoutTools draws the offset editor and the find editor with its
+// buttons.
+func (v *hexView) layoutTools(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	button := func(click *widget.Clickable, text string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return material.Clickable(gtx, click, func(gtx layout.Context) layout.Dimensions {
+				return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Body2(theme, text).Layout)
+			})
+		})
+	}
+	editor := func(e *widget.Editor, hint string) layout.FlexChild {
+		return layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				style := material.Editor(theme, e, hint)
+				style.TextSize = theme.TextSize * 14 / 16
+				return style.Layout(gtx)
+			})
+		})
+	}
+
+	mode, hint := "Hex", "Find bytes, like DE AD BE EF"
+	if v.findText {
+		mode, hint = "Text", "Find text"
+	}
+
+	return layout.Inset{Left: unit.Dp(4), Right: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					editor(&v.offsetEditor, "Go to offset, like 0x1F00"),
+				)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					editor(&v.findEditor, hint),
+					button(&v.modeButton, mode),
+					button(&v.nextButton, "Next"),
+				)
+			}),
+		)
+	})
+}
+
+// layoutRows draws the rows of bytes in view, with as many bytes on a row as
+// fit.
+func (v *hexView) layoutRows(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	size := v.size()
+	v.measure(gtx, theme)
+
+	v.digits = max(8, len(strconv.FormatInt(max(size-1, 0), 16)))
+	v.columns = maxColumns
+	for v.columns > 4 && float32(v.asciiStart()+v.columns)*v.charWidth > float32(gtx.Constraints.Max.X-gtx.Dp(unit.Dp(12))) {
+		v.columns /= 2
+	}
+	rows := int((size + int64(v.columns) - 1) / int64(v.columns))
+
+	area := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	event.Op(gtx.Ops, v)
+	dims := material.List(theme, &v.list).Layout(gtx, rows, func(gtx layout.Context, row int) layout.Dimensions {
+		return v.layoutRow(gtx, theme, int64(row))
+	})
+	area.Pop()
+
+	return dims
+}
+
+// measure finds the size of a character of the bytes.
+func (v *hexView) measure(gtx layout.Context, theme *material.Theme) {
+	const sample = "0123456789ABCDEF"
+	macro := op.Record(gtx.Ops)
+	dims := v.label(theme, sample).Layout(gtx)
+	macro.Stop()
+
+	v.charWidth = float32(dims.Size.X) / float32(len(sample))
+	v.rowHeight = dims.Size.Y
+}
+
+// label returns the label of text in the monospace font of the bytes.
+func (v *hexView) label(theme *material.Theme, text string) material.LabelStyle {
+	label := material.Label(theme, hexTextSize, text)
+	label.Font.Typeface = "monospace"
+	label.MaxLines = 1
+	return label
+}
+
+// layoutRow draws a row: its offset, its bytes in hex and as ASCII, the
+// selected ones highlighted.
+func (v *hexView) layoutRow(gtx layout.Context, theme *material.Theme, row int64) layout.Dimensions {
+	start := row * int64(v.columns)
+	buf := make([]byte, min(int64(v.columns), v.size()-start))
+	n, err := v.pager.ReadAt(buf, start)
+	unreadable := err != nil && err != io.EOF
+
+	if first, last, ok := v.selection(); ok && last >= start && first < start+int64(len(buf)) {
+		from := int(max(first, start) - start)
+		to := int(min(last, start+int64(len(buf))-1) - start)
+
+		highlight := theme.Palette.ContrastBg
+		highlight.A = 0x50
+		fill := func(fromColumn, toColumn int) {
+			rect := image.Rect(int(float32(fromColumn)*v.charWidth), 0, int(float32(toColumn)*v.charWidth), v.rowHeight)
+			paint.FillShape(gtx.Ops, highlight, clip.Rect(rect).Op())
+		}
+		fill(v.hexColumn(from), v.hexColumn(to)+2)
+		fill(v.asciiStart()+from, v.asciiStart()+to+1)
+	}
+
+	var bytes strings.Builder
+	for i := 0; i < v.columns; i++ {
+		if i == v.columns/2 {
+			bytes.WriteByte(' ')
+		}
+		switch {
+		case i >= len(buf):
+			bytes.WriteString("   ")
+		case i >= n || unreadable:
+			bytes.WriteString("?? ")
+		default:
+			fmt.Fprintf(&bytes, "%02X ", buf[i])
+		}
+	}
+	bytes.WriteByte(' ')
+	for i := range buf {
+		switch {
+		case i >= n || unreadable:
+			bytes.WriteByte('?')
+		case buf[i] >= 0x20 && buf[i] < 0x7f:
+			bytes.WriteByte(buf[i])
+		default:
+			bytes.WriteByte('.')
+		}
+	}
+
+	offset := v.label(theme, fmt.Sprintf("%0*X", v.digits, start))
+	offset.Color.A = 0x90
+	offset.Layout(gtx)
+
+	stack := op.Offset(image.Pt(int(float32(v.hexStart())*v.charWidth), 0)).Push(gtx.Ops)
+	gtx.Constraints.Min = image.Point{}
+	v.label(theme, bytes.String()).Layout(gtx)
+	stack.Pop()
+
+	return layout.Dimensions{Size: image.Pt(gtx.Constraints.Max.X, v.rowHeight)}
+}
+
+// layoutStatus draws where the selection is, the copy button and the
+// message of the last search or copy.
+func (v *hexView) layoutStatus(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	text := fmt.Sprintf("%s, %d bytes", entry.FormatSize(v.size()), v.size())
+	first, last, selected := v.selection()
+	switch {
+	case selected && first == last:
+		text = fmt.Sprintf("Offset 0x%X (%d)", first, first)
+	case selected:
+		text = fmt.Sprintf("%d bytes selected, 0x%X to 0x%X", last-first+1, first, last)
+	}
+	if v.message != "" {
+		text += " · " + v.message
+	}
+
+	return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				label := material.Body2(theme, text)
+				label.Color.A = 0x90
+				return label.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !selected {
+					return layout.Dimensions{}
+				}
+				return material.Clickable(gtx, &v.copyButton, func(gtx layout.Context) layout.Dimensions {
+					return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Body2(theme, "Copy hex").Layout)
+				})
+			}),
+		)
+	})
+}
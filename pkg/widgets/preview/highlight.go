@@ -0,0 +1,358 @@
+package preview
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// class is what a piece of code is, each drawn in its own color.
+type class uint8
+
+const (
+	plain class = iota
+	keyword
+	literal
+	number
+	comment
+)
+
+// span is a piece of a line of the same class.
+type span struct {
+	text  string
+	class class
+}
+
+// language is how the code of a language is highlighted.
+type language struct {
+	lineComments []string
+	// blockComment starts and ends comments that can span lines
+	blockComment [2]string
+	// quotes start and end strings, the ones in multiline can span lines
+	quotes    string
+	multiline string
+	// raw are the quotes of strings without escapes
+	raw      string
+	keywords map[string]bool
+}
+
+// words returns the set of the words in list.
+func words(list string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(list) {
+		set[word] = true
+	}
+	return set
+}
+
+var (
+	goLanguage = &language{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		raw:          "`",
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto
+			if import interface map package range return select struct switch type var
+			true false nil iota bool byte rune string error int int8 int16 int32 int64
+			uint uint8 uint16 uint32 uint64 uintptr float32 float64 complex64 complex128 any`),
+	}
+	cLanguage = &language{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords: words(`auto break case char const continue default do double else enum extern float for
+			goto if inline int long register return short signed sizeof static struct switch typedef
+			union unsigned void volatile while bool true false nullptr class namespace template
+			typename public private protected virtual override new delete this using try catch throw
+			#include #define #ifdef #ifndef #endif #if #else #pragma`),
+	}
+	javaLanguage = &language{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+		keywords: words(`abstract boolean break byte case catch char class const continue default do double
+			else enum extends final finally float for if implements import instanceof int interface
+			long native new package private protected public return short static super switch
+			synchronized this throw throws try void volatile while true false null var val fun
+			object when data sealed override`),
+	}
+	jsLanguage = &language{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		keywords: words(`async await break case catch class const continue debugger default delete do else
+			export extends finally for from function if import in instanceof let new of return
+			static super switch this throw try typeof var void while yield true false null
+			undefined interface type enum implements private public protected readonly`),
+	}
+	rustLanguage = &language{
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"",
+		keywords: words(`as async await break const continue crate else enum extern false fn for if impl in
+			let loop match mod move mut pub ref return self Self static struct super trait true
+			type unsafe use where while dyn i8 i16 i32 i64 u8 u16 u32 u64 f32 f64 usize isize
+			bool char str String Vec Option Some None Result Ok Err`),
+	}
+	pythonLanguage = &language{
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		keywords: words(`and as assert async await break class continue def del elif else except False
+			finally for from global if import in is lambda None nonlocal not or pass raise
+			return True try while with yield self`),
+	}
+	shellLanguage = &language{
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		multiline:    "\"'",
+		keywords: words(`if then else elif fi case esac for while until do done in function return exit
+			local export readonly set unset shift source echo cd true false`),
+	}
+	configLanguage = &language{
+		lineComments: []string{"#", ";"},
+		quotes:       "\"'",
+		keywords:     words(`true false yes no on off null`),
+	}
+	jsonLanguage = &language{
+		quotes:   "\"",
+		keywords: words(`true false null`),
+	}
+	markupLanguage = &language{
+		blockComment: [2]string{"<!--", "-->"},
+		quotes:       "\"'",
+	}
+	sqlLanguage = &language{
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"",
+		keywords: words(`select from where insert into values update set delete create table drop alter
+			index view join left right inner outer on and or not null is as order by group
+			having limit offset primary key foreign references default union all distinct
+			SELECT FROM WHERE INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE DROP ALTER
+			INDEX VIEW JOIN LEFT RIGHT INNER OUTER ON AND OR NOT NULL IS AS ORDER BY GROUP
+			HAVING LIMIT OFFSET PRIMARY KEY FOREIGN REFERENCES DEFAULT UNION ALL DISTINCT`),
+	}
+)
+
+// languages are the languages of the files by extension.
+var languages = map[string]*language{
+	"go":    goLanguage,
+	"c":     cLanguage,
+	"h":     cLanguage,
+	"cc":    cLanguage,
+	"cpp":   cLanguage,
+	"hpp":   cLanguage,
+	"cs":    javaLanguage,
+	"java":  javaLanguage,
+	"kt":    javaLanguage,
+	"js":    jsLanguage,
+	"jsx":   jsLanguage,
+	"mjs":   jsLanguage,
+	"ts":    jsLanguage,
+	"tsx":   jsLanguage,
+	"rs":    rustLanguage,
+	"py":    pythonLanguage,
+	"rb":    pythonLanguage,
+	"sh":    shellLanguage,
+	"bash":  shellLanguage,
+	"zsh":   shellLanguage,
+	"yaml":  configLanguage,
+	"yml":   configLanguage,
+	"toml":  configLanguage,
+	"ini":   configLanguage,
+	"conf":  configLanguage,
+	"cfg":   configLanguage,
+	"env":   configLanguage,
+	"mod":   configLanguage,
+	"json":  jsonLanguage,
+	"html":  markupLanguage,
+	"htm":   markupLanguage,
+	"xml":   markupLanguage,
+	"svg":   markupLanguage,
+	"sql":   sqlLanguage,
+	"proto": cLanguage,
+}
+
+// names are the languages of the files without an extension telling it.
+var names = map[string]*language{
+	"makefile":   shellLanguage,
+	"dockerfile": shellLanguage,
+	".bashrc":    shellLanguage,
+	".profile":   shellLanguage,
+	".gitconfig": configLanguage,
+}
+
+// languageOf returns the language of the file at path, or nil for plain
+// text.
+func languageOf(path string) *language {
+	name := strings.ToLower(filepath.Base(path))
+	if lang, ok := names[name]; ok {
+		return lang
+	}
+	return languages[strings.TrimPrefix(filepath.Ext(name), ".")]
+}
+
+// highlight splits text in lines of spans of the same class. Tabs are
+// expanded so the lines line up.
+func highlight(text string, lang *language) [][]span {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+	lines := strings.Split(text, "\n")
+
+	h := highlighter{lang: lang}
+	spans := make([][]span, len(lines))
+	for i, line := range lines {
+		spans[i] = h.line(line)
+	}
+	return spans
+}
+
+// highlighter splits lines in spans, keeping what is open at the end of a
+// line, a comment or a string, for the next one.
+type highlighter struct {
+	lang *language
+	// inComment is set inside a block comment, quote is the quote of the
+	// string the line starts in
+	inComment bool
+	quote     byte
+	spans     []span
+}
+
+// add appends text of class c to the spans of the line.
+func (h *highlighter) add(text string, c class) {
+	if text == "" {
+		return
+	}
+	if n := len(h.spans); n > 0 && h.spans[n-1].class == c {
+		h.spans[n-1].text += text
+		return
+	}
+	h.spans = append(h.spans, span{text: text, class: c})
+}
+
+// line returns the spans of line.
+func (h *highlighter) line(line string) []span {
+	h.spans = nil
+	if h.lang == nil {
+		h.add(line, plain)
+		return h.spans
+	}
+
+	lang := h.lang
+	for i := 0; i < len(line); {
+		rest := line[i:]
+
+		switch {
+		case h.inComment:
+			end := strings.Index(rest, lang.blockComment[1])
+			if end < 0 {
+				h.add(rest, comment)
+				return h.spans
+			}
+			end += len(lang.blockComment[1])
+			h.add(rest[:end], comment)
+			h.inComment = false
+			i += end
+			continue
+		case h.quote != 0:
+			end := h.stringEnd(rest, h.quote)
+			if end < 0 {
+				h.add(rest, literal)
+				return h.spans
+			}
+			h.add(rest[:end], literal)
+			h.quote = 0
+			i += end
+			continue
+		}
+
+		if hasAnyPrefix(rest, lang.lineComments) {
+			h.add(rest, comment)
+			return h.spans
+		}
+		if lang.blockComment[0] != "" && strings.HasPrefix(rest, lang.blockComment[0]) {
+			h.add(lang.blockComment[0], comment)
+			h.inComment = true
+			i += len(lang.blockComment[0])
+			continue
+		}
+
+		c := line[i]
+		switch {
+		case strings.IndexByte(lang.quotes, c) >= 0:
+			end := h.stringEnd(rest[1:], c)
+			if end < 0 {
+				h.add(rest, literal)
+				// only some strings go on, the others end with the line
+				if strings.IndexByte(lang.multiline, c) >= 0 {
+					h.quote = c
+				}
+				return h.spans
+			}
+			h.add(rest[:end+1], literal)
+			i += end + 1
+		case c >= '0' && c <= '9' && (i == 0 || !isWordByte(line[i-1])):
+			end := 1
+			for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '.') {
+				end++
+			}
+			h.add(rest[:end], number)
+			i += end
+		case isWordStart(rest):
+			end := 1
+			for end < len(rest) && isWordByte(rest[end]) {
+				end++
+			}
+			word := rest[:end]
+			if lang.keywords[word] {
+				h.add(word, keyword)
+			} else {
+				h.add(word, plain)
+			}
+			i += end
+		default:
+			_, size := utf8.DecodeRuneInString(rest)
+			h.add(rest[:size], plain)
+			i += size
+		}
+	}
+	return h.spans
+}
+
+// stringEnd returns the length of rest up to and including the quote ending
+// the string it is in, or -1 if the string does not end on the line.
+func (h *highlighter) stringEnd(rest string, quote byte) int {
+	escapes := strings.IndexByte(h.lang.raw, quote) < 0
+	for i := 0; i < len(rest); i++ {
+		switch {
+		case escapes && rest[i] == '\\':
+			i++
+		case rest[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordStart tells if s starts with a word, keywords of C like #include
+// included.
+func isWordStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || r == '#' && len(s) > 1 && unicode.IsLetter(rune(s[1])) || unicode.IsLetter(r)
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b >= utf8.RuneSelf
+}
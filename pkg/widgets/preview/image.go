@@ -0,0 +1,213 @@
+package preview
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+const (
+	// zoomStep is how much a zoom in or out changes the zoom
+	zoomStep = 1.25
+	// minZoom and maxZoom are how far the image can be zoomed out and in,
+	// in screen pixels per image pixel
+	minZoom = 1.0 / 32
+	maxZoom = 32
+)
+
+// imageView shows an image fit in the pane, zoomed with the buttons or with
+// the wheel and ctrl, and dragged around when larger than the pane.
+type imageView struct {
+	img image.Image
+	op  paint.ImageOp
+
+	// zoom is the size of an image pixel in screen pixels, 0 to fit the
+	// image in the pane
+	zoom float32
+	// offset is how far the center of the image is moved from the center
+	// of the pane
+	offset f32.Point
+	// fit is the zoom fitting the image in the pane in the last frame
+	fit float32
+
+	dragging bool
+	last     f32.Point
+
+	fitButton, actualButton, inButton, outButton widget.Clickable
+}
+
+// set shows img, fit in the pane.
+func (v *imageView) set(img image.Image) {
+	*v = imageView{img: img}
+	if img != nil {
+		v.op = paint.NewImageOp(img)
+	}
+}
+
+// scale returns the size of an image pixel in screen pixels.
+func (v *imageView) scale() float32 {
+	if v.zoom == 0 {
+		return v.fit
+	}
+	return v.zoom
+}
+
+// zoomBy zooms by factor, keeping the center of the pane where it is on the
+// image.
+func (v *imageView) zoomBy(factor float32) {
+	old := v.scale()
+	if old == 0 {
+		return
+	}
+	zoom := min(max(old*factor, minZoom), maxZoom)
+	v.offset = v.offset.Mul(zoom / old)
+	v.zoom = zoom
+}
+
+// update zooms with the buttons and the wheel and drags the image.
+func (v *imageView) update(gtx layout.Context) {
+	if v.fitButton.Clicked(gtx) {
+		v.zoom = 0
+		v.offset = f32.Point{}
+	}
+	if v.actualButton.Clicked(gtx) {
+		v.zoomBy(1 / v.scale())
+	}
+	if v.inButton.Clicked(gtx) {
+		v.zoomBy(zoomStep)
+	}
+	if v.outButton.Clicked(gtx) {
+		v.zoomBy(1 / zoomStep)
+	}
+
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target:  v,
+			Kinds:   pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel | pointer.Scroll,
+			ScrollX: pointer.ScrollRange{Min: math.MinInt32, Max: math.MaxInt32},
+			ScrollY: pointer.ScrollRange{Min: math.MinInt32, Max: math.MaxInt32},
+		})
+		if !ok {
+			break
+		}
+
+		e, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+
+		switch e.Kind {
+		case pointer.Press:
+			v.dragging = e.Buttons == pointer.ButtonPrimary
+			v.last = e.Position
+		case pointer.Drag:
+			if v.dragging {
+				v.offset = v.offset.Add(e.Position.Sub(v.last))
+				v.last = e.Position
+			}
+		case pointer.Release, pointer.Cancel:
+			v.dragging = false
+		case pointer.Scroll:
+			// ctrl and the wheel zoom, the wheel alone moves the image
+			if e.Modifiers.Contain(key.ModShortcut) {
+				if e.Scroll.Y < 0 {
+					v.zoomBy(zoomStep)
+				} else if e.Scroll.Y > 0 {
+					v.zoomBy(1 / zoomStep)
+				}
+				continue
+			}
+			v.offset = v.offset.Sub(e.Scroll)
+		}
+	}
+}
+
+// Layout draws the zoom buttons over the image.
+func (v *imageView) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	v.update(gtx)
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return v.layoutButtons(gtx, theme)
+		}),
+		layout.Flexed(1, v.layoutImage),
+	)
+}
+
+// layoutButtons draws the zoom buttons and the zoom.
+func (v *imageView) layoutButtons(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	button := func(click *widget.Clickable, text string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return material.Clickable(gtx, click, func(gtx layout.Context) layout.Dimensions {
+				return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Body2(theme, text).Layout)
+			})
+		})
+	}
+
+	return layout.Inset{Left: unit.Dp(4), Right: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			button(&v.fitButton, "Fit"),
+			button(&v.actualButton, "100%"),
+			button(&v.outButton, "−"),
+			button(&v.inButton, "+"),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				label := material.Body2(theme, fmt.Sprintf("%.0f%%", v.scale()*100))
+				label.Color.A = 0x90
+				return layout.E.Layout(gtx, label.Layout)
+			}),
+		)
+	})
+}
+
+// layoutImage draws the image zoomed, its center moved from the center of
+// the pane by the offset.
+func (v *imageView) layoutImage(gtx layout.Context) layout.Dimensions {
+	size := gtx.Constraints.Max
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, v)
+
+	bounds := v.img.Bounds()
+	if bounds.Empty() || size.X <= 0 || size.Y <= 0 {
+		return layout.Dimensions{Size: size}
+	}
+
+	// small images are not blown up to fit, only shown at their size
+	v.fit = min(float32(size.X)/float32(bounds.Dx()), float32(size.Y)/float32(bounds.Dy()), gtx.Metric.PxPerDp)
+	scale := v.scale()
+
+	// the image can't be dragged out of the pane
+	shown := f32.Pt(float32(bounds.Dx())*scale, float32(bounds.Dy())*scale)
+	limit := f32.Pt(max(shown.X-float32(size.X), 0)/2, max(shown.Y-float32(size.Y), 0)/2)
+	v.offset.X = min(max(v.offset.X, -limit.X), limit.X)
+	v.offset.Y = min(max(v.offset.Y, -limit.Y), limit.Y)
+
+	origin := f32.Pt(float32(size.X)-shown.X, float32(size.Y)-shown.Y).Mul(0.5).Add(v.offset)
+	// zoomed in far, the pixels are shown as squares
+	if scale >= 4 {
+		v.op.Filter = paint.FilterNearest
+	} else {
+		v.op.Filter = paint.FilterLinear
+	}
+
+	transform := f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(scale, scale)).Offset(origin)
+	defer op.Affine(transform).Push(gtx.Ops).Pop()
+	v.op.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+
+	if v.dragging {
+		pointer.CursorGrabbing.Add(gtx.Ops)
+	}
+	return layout.Dimensions{Size: size}
+}
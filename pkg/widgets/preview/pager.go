@@ -0,0 +1,119 @@
+package preview
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// pageSize is how much of a file is read at a time
+	pageSize = 64 << 10
+	// maxPages is how many pages are kept, the ones used least recently are
+	// dropped first
+	maxPages = 64
+)
+
+// pager reads a file a page at a time and keeps the pages read last, so
+// files of any size are shown without being read whole. It is safe for
+// concurrent use.
+type pager struct {
+	file *os.File
+	size int64
+
+	mu    sync.Mutex
+	pages map[int64][]byte
+	// used are the pages kept, the one used last last
+	used []int64
+}
+
+// openPager opens the file at path for paged reads.
+func openPager(path string) (*pager, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &pager{
+		file:  file,
+		size:  info.Size(),
+		pages: map[int64][]byte{},
+	}, nil
+}
+
+// Size returns the size of the file when it was opened.
+func (p *pager) Size() int64 {
+	return p.size
+}
+
+// ReadAt reads len(buf) bytes at off from the pages of the file, reading the
+// ones not kept.
+func (p *pager) ReadAt(buf []byte, off int64) (int, error) {
+	read := 0
+	for read < len(buf) {
+		if off >= p.size {
+			return read, io.EOF
+		}
+
+		page, err := p.page(off / pageSize)
+		if err != nil {
+			return read, err
+		}
+		start := int(off % pageSize)
+		if start >= len(page) {
+			return read, io.EOF
+		}
+
+		n := copy(buf[read:], page[start:])
+		read += n
+		off += int64(n)
+	}
+	return read, nil
+}
+
+// page returns page n of the file, from the kept pages if it is one.
+func (p *pager) page(n int64) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if page, ok := p.pages[n]; ok {
+		p.use(n)
+		return page, nil
+	}
+
+	page := make([]byte, pageSize)
+	read, err := p.file.ReadAt(page, n*pageSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	page = page[:read]
+
+	if len(p.used) >= maxPages {
+		delete(p.pages, p.used[0])
+		p.used = p.used[1:]
+	}
+	p.pages[n] = page
+	p.used = append(p.used, n)
+	return page, nil
+}
+
+// use moves page n to the end of the used pages.
+func (p *pager) use(n int64) {
+	for i, used := range p.used {
+		if used == n {
+			copy(p.used[i:], p.used[i+1:])
+			p.used[len(p.used)-1] = n
+			return
+		}
+	}
+}
+
+// Close closes the file.
+func (p *pager) Close() error {
+	return p.file.Close()
+}
@@ -0,0 +1,438 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/ShakedGold/Gole/pkg/explorer/thumbnail"
+	"github.com/ShakedGold/Gole/pkg/widgets/entry"
+)
+
+const (
+	// MaxTextSize is how much of a text file is shown, the rest is cut
+	MaxTextSize = 512 << 10
+	// maxImageSize is the longest side images are shown with, larger ones
+	// are scaled down
+	maxImageSize = 4096
+	// iconSize is how large the icon of files with nothing else to show is
+	iconSize = unit.Dp(96)
+)
+
+// Preview is the pane next to the entries showing the selected file: text
+// highlighted, images zoomable, binary files in hex and only the details of
+// the others. The content is read in the background.
+type Preview struct {
+	// Invalidate, if set, is called when the content is read so a new frame
+	// shows it
+	Invalidate func()
+	// Close is clicked to close the pane
+	Close widget.Clickable
+
+	// entry is the one shown, shown is not set when there is none
+	entry entry.Entry
+	shown bool
+	// loading is set until the content of the entry is read
+	loading bool
+	err     error
+
+	text  textView
+	image imageView
+	hex   hexView
+	// mode is what the content of the entry is shown as
+	mode mode
+	// details are the size and the like of the entry
+	details []detail
+	list    widget.List
+
+	mu sync.Mutex
+	// generation changes with the entry shown, loads of an older generation
+	// are stale
+	generation int
+	loaded     *content
+	cancel     context.CancelFunc
+}
+
+// mode is how the content of an entry is shown.
+type mode int
+
+const (
+	modeDetails mode = iota
+	modeText
+	modeImage
+	modeHex
+)
+
+// content is the content of an entry, read in the background.
+type content struct {
+	mode      mode
+	lines     [][]span
+	truncated bool
+	image     image.Image
+	pager     *pager
+	// dimensions are those of the image file, the image may be scaled down
+	dimensions image.Point
+	err        error
+}
+
+// detail is a line of the details of an entry.
+type detail struct {
+	name, value string
+}
+
+// New creates an empty preview pane.
+func New() *Preview {
+	p := &Preview{
+		cancel: func() {},
+	}
+	p.list.Axis = layout.Vertical
+	p.hex.init()
+	return p
+}
+
+// Show shows e, starting to read its content. Showing the entry already
+// shown does nothing unless it was modified since.
+func (p *Preview) Show(e entry.Entry) {
+	if p.shown && p.entry.Path == e.Path && p.entry.ModTime().Equal(e.ModTime()) && p.entry.Size() == e.Size() {
+		return
+	}
+
+	p.mu.Lock()
+	p.cancel()
+	p.generation++
+	generation := p.generation
+	p.dropLoaded()
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.mu.Unlock()
+
+	p.entry = e
+	p.shown = true
+	p.err = nil
+	p.mode = modeFor(e)
+	p.details = detailsOf(e)
+	p.text.set(nil, false)
+	p.image.set(nil)
+	p.hex.set(nil, nil)
+	p.list.Position = layout.Position{}
+
+	p.loading = p.mode != modeDetails
+	if p.loading {
+		go p.load(ctx, e, generation)
+	}
+}
+
+// Clear shows no entry.
+func (p *Preview) Clear() {
+	if !p.shown {
+		return
+	}
+
+	p.mu.Lock()
+	p.cancel()
+	p.generation++
+	p.dropLoaded()
+	p.mu.Unlock()
+
+	p.entry = entry.Entry{}
+	p.shown = false
+	p.loading = false
+	p.text.set(nil, false)
+	p.image.set(nil)
+	p.hex.set(nil, nil)
+}
+
+// dropLoaded drops the content read and not shown, closing its file. It is
+// called with mu held.
+func (p *Preview) dropLoaded() {
+	if p.loaded != nil && p.loaded.pager != nil {
+		p.loaded.pager.Close()
+	}
+	p.loaded = nil
+}
+
+// modeFor returns how the content of e is shown.
+func modeFor(e entry.Entry) mode {
+	switch {
+	case e.IsFolder || e.Info == nil || !e.Info.Mode().IsRegular():
+		return modeDetails
+	case thumbnail.Supported(e.Type.MIME):
+		return modeImage
+	case !e.Type.IsBinary:
+		return modeText
+	}
+	return modeHex
+}
+
+// load reads the content of e in the background.
+func (p *Preview) load(ctx context.Context, e entry.Entry, generation int) {
+	c := &content{mode: modeFor(e)}
+	switch c.mode {
+	case modeImage:
+		c.image, c.err = thumbnail.Make(ctx, e.Path, maxImageSize)
+		if config, err := imageConfig(e.Path); err == nil {
+			c.dimensions = image.Pt(config.Width, config.Height)
+		}
+	case modeText:
+		var text string
+		text, c.truncated, c.err = readText(e.Path)
+		if c.err == nil {
+			c.lines = highlight(text, languageOf(e.Path))
+		}
+	case modeHex:
+		// only the pages in view are read, however large the file is
+		c.pager, c.err = openPager(e.Path)
+	}
+
+	p.mu.Lock()
+	if p.generation != generation || ctx.Err() != nil {
+		p.mu.Unlock()
+		if c.pager != nil {
+			c.pager.Close()
+		}
+		return
+	}
+	p.loaded = c
+	p.mu.Unlock()
+
+	if p.Invalidate != nil {
+		p.Invalidate()
+	}
+}
+
+// readText returns the start of the text file at path, at most MaxTextSize
+// bytes cut at the end of a line, and if there is more of it.
+func readText(path string) (string, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, MaxTextSize+1))
+	if err != nil {
+		return "", false, err
+	}
+
+	truncated := len(content) > MaxTextSize
+	if truncated {
+		content = content[:MaxTextSize]
+		if end := strings.LastIndexByte(string(content), '\n'); end >= 0 {
+			content = content[:end]
+		}
+	}
+	return strings.ToValidUTF8(string(content), "�"), truncated, nil
+}
+
+// flush takes in the content read since the last frame.
+func (p *Preview) flush() {
+	p.mu.Lock()
+	c := p.loaded
+	p.loaded = nil
+	p.mu.Unlock()
+
+	if c == nil {
+		return
+	}
+
+	p.loading = false
+	p.err = c.err
+	if c.err != nil {
+		// the details are shown of what can't be read
+		p.mode = modeDetails
+		return
+	}
+
+	switch c.mode {
+	case modeText:
+		p.text.set(c.lines, c.truncated)
+		lines := fmt.Sprint(len(c.lines))
+		if c.truncated {
+			lines = "More than " + lines
+		}
+		p.details = append(p.details, detail{name: "Lines", value: lines})
+	case modeImage:
+		p.image.set(c.image)
+		if c.dimensions != (image.Point{}) {
+			p.details = append(p.details, detail{name: "Dimensions", value: fmt.Sprintf("%d × %d", c.dimensions.X, c.dimensions.Y)})
+		}
+	case modeHex:
+		p.hex.set(c.pager, p.Invalidate)
+	}
+}
+
+// imageConfig returns the dimensions of the image file at path.
+func imageConfig(path string) (image.Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	return config, err
+}
+
+// detailsOf returns the details shown of e.
+func detailsOf(e entry.Entry) []detail {
+	details := []detail{
+		{name: "Type", value: e.TypeName()},
+	}
+	if e.Type.MIME != "" {
+		details = append(details, detail{name: "MIME", value: e.Type.MIME})
+	}
+	if !e.IsFolder {
+		details = append(details, detail{name: "Size", value: fmt.Sprintf("%s (%d bytes)", entry.FormatSize(e.Size()), e.Size())})
+	}
+	if e.Info != nil {
+		details = append(details,
+			detail{name: "Modified", value: entry.FormatTime(e.ModTime())},
+			detail{name: "Permissions", value: e.Permissions()},
+			detail{name: "Owner", value: e.Owner()},
+		)
+	}
+	if target, err := os.Readlink(e.Path); err == nil {
+		details = append(details, detail{name: "Target", value: target})
+	}
+	return append(details, detail{name: "Location", value: filepath.Dir(e.Path)})
+}
+
+// Layout draws the pane.
+func (p *Preview) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	p.flush()
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.layoutTitle(gtx, theme)
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return p.layoutContent(gtx, theme)
+		}),
+	)
+}
+
+// layoutTitle draws the name of the entry and the close button.
+func (p *Preview) layoutTitle(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	title := "Preview"
+	if p.shown {
+		title = p.entry.Name()
+	}
+
+	return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				label := material.Body1(theme, title)
+				label.Font.Weight = font.SemiBold
+				label.MaxLines = 1
+				return label.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return material.Clickable(gtx, &p.Close, func(gtx layout.Context) layout.Dimensions {
+					return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Body2(theme, "Close").Layout)
+				})
+			}),
+		)
+	})
+}
+
+// layoutContent draws the content of the entry above its details, or only
+// its details when there is no content to show.
+func (p *Preview) layoutContent(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	if !p.shown {
+		return layoutNote(gtx, theme, "Select a file to preview it")
+	}
+
+	content := func(gtx layout.Context) layout.Dimensions {
+		switch {
+		case p.loading:
+			return layoutNote(gtx, theme, "Loading…")
+		case p.mode == modeText:
+			return p.text.Layout(gtx, theme)
+		case p.mode == modeImage:
+			return p.image.Layout(gtx, theme)
+		case p.mode == modeHex:
+			return p.hex.Layout(gtx, theme)
+		}
+		return p.layoutIcon(gtx, theme)
+	}
+
+	if p.mode == modeDetails && !p.loading {
+		return material.List(theme, &p.list).Layout(gtx, 1, func(gtx layout.Context, index int) layout.Dimensions {
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(content),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return p.layoutDetails(gtx, theme)
+				}),
+			)
+		})
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Flexed(1, content),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.layoutDetails(gtx, theme)
+		}),
+	)
+}
+
+// layoutIcon draws the icon of the entry large, and why its content is not
+// shown if it could not be read.
+func (p *Preview) layoutIcon(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	return layout.UniformInset(unit.Dp(16)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min.X = gtx.Constraints.Max.X
+		return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return p.entry.LayoutIcon(gtx, iconSize)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if p.err == nil {
+					return layout.Dimensions{}
+				}
+				label := material.Body2(theme, p.err.Error())
+				label.Color.A = 0x90
+				return layout.Inset{Top: unit.Dp(8)}.Layout(gtx, label.Layout)
+			}),
+		)
+	})
+}
+
+// layoutDetails draws the details of the entry, a name and a value on every
+// line.
+func (p *Preview) layoutDetails(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	children := make([]layout.FlexChild, len(p.details))
+	for i, d := range p.details {
+		d := d
+		children[i] = layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					gtx.Constraints.Min.X = gtx.Dp(unit.Dp(88))
+					label := material.Body2(theme, d.name)
+					label.Color.A = 0x90
+					return label.Layout(gtx)
+				}),
+				layout.Flexed(1, material.Body2(theme, d.value).Layout),
+			)
+		})
+	}
+
+	return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
+// layoutNote draws text in the middle of the pane.
+func layoutNote(gtx layout.Context, theme *material.Theme, text string) layout.Dimensions {
+	label := material.Body2(theme, text)
+	label.Color.A = 0x90
+	return layout.Center.Layout(gtx, label.Layout)
+}
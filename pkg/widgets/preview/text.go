@@ -0,0 +1,93 @@
+package preview
+
+import (
+	"image/color"
+	"strconv"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// colors are the colors of the classes of code, plain code is in the color
+// of the theme.
+var colors = map[class]color.NRGBA{
+	keyword: {R: 0x7b, G: 0x1f, B: 0xa2, A: 0xff},
+	literal: {R: 0x2e, G: 0x7d, B: 0x32, A: 0xff},
+	number:  {R: 0xd8, G: 0x43, B: 0x15, A: 0xff},
+	comment: {R: 0x75, G: 0x75, B: 0x75, A: 0xff},
+}
+
+// textView shows the lines of a text file, highlighted, after their numbers.
+type textView struct {
+	lines [][]span
+	// truncated is set when the file is longer than what is shown
+	truncated bool
+	list      widget.List
+}
+
+// set shows lines, scrolled to the top.
+func (v *textView) set(lines [][]span, truncated bool) {
+	v.lines = lines
+	v.truncated = truncated
+	v.list.Axis = layout.Vertical
+	v.list.Position = layout.Position{}
+}
+
+// Layout draws the lines in view, and a note at the end of a truncated file.
+func (v *textView) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	count := len(v.lines)
+	if v.truncated {
+		count++
+	}
+	// wide enough for the largest line number
+	numberWidth := len(strconv.Itoa(len(v.lines)))
+
+	return material.List(theme, &v.list).Layout(gtx, count, func(gtx layout.Context, index int) layout.Dimensions {
+		if index == len(v.lines) {
+			label := material.Body2(theme, "Only the start of the file is shown")
+			label.Font.Style = font.Italic
+			label.Color.A = 0x90
+			return layout.UniformInset(unit.Dp(4)).Layout(gtx, label.Layout)
+		}
+		return layoutLine(gtx, theme, index+1, numberWidth, v.lines[index])
+	})
+}
+
+// layoutLine draws a line and its number, right aligned in width digits.
+func layoutLine(gtx layout.Context, theme *material.Theme, number int, width int, spans []span) layout.Dimensions {
+	text := strconv.Itoa(number)
+	for len(text) < width {
+		text = " " + text
+	}
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			label := material.Body2(theme, text+"  ")
+			label.Color.A = 0x90
+			label.Font.Typeface = "monospace"
+			return label.Layout(gtx)
+		}),
+	}
+	for _, s := range spans {
+		s := s
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			label := material.Body2(theme, s.text)
+			label.MaxLines = 1
+			label.Font.Typeface = "monospace"
+			if c, ok := colors[s.class]; ok {
+				label.Color = c
+			}
+			if s.class == keyword {
+				label.Font.Weight = font.SemiBold
+			}
+			return label.Layout(gtx)
+		}))
+	}
+
+	return layout.Inset{Left: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+	})
+}
@@ -0,0 +1,291 @@
+package results
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/ShakedGold/Gole/pkg/explorer/search"
+)
+
+const (
+	// MaxMatches is how many matches are kept, the search stops after that
+	MaxMatches = 5000
+	// maxShownLength is how much of a matching line is shown
+	maxShownLength = 240
+)
+
+// Results is the pane listing the lines found by a content search, grouped
+// by file.
+type Results struct {
+	// Root is where the search started, paths are shown relative to it
+	Root string
+	// OnOpen is called with a match when it is clicked
+	OnOpen func(search.Match)
+	// Invalidate, if set, is called when matches are added so a new frame
+	// shows them
+	Invalidate func()
+	// Close is clicked to close the pane
+	Close widget.Clickable
+
+	files   []*file
+	byPath  map[string]*file
+	matches int
+	list    widget.List
+
+	mu sync.Mutex
+	// generation changes on every reset, adders of an older generation are
+	// stale
+	generation int
+	queue      []search.Match
+}
+
+// file is a file with matches and the clickables of its lines.
+type file struct {
+	path    string
+	matches []search.Match
+	clicks  []widget.Clickable
+}
+
+// New creates an empty results pane.
+func New() *Results {
+	r := &Results{
+		byPath: map[string]*file{},
+	}
+	r.list.Axis = layout.Vertical
+	return r
+}
+
+// Reset empties the pane for a search under root.
+func (r *Results) Reset(root string) {
+	r.mu.Lock()
+	r.generation++
+	r.queue = nil
+	r.mu.Unlock()
+
+	r.Root = root
+	r.files = nil
+	r.byPath = map[string]*file{}
+	r.matches = 0
+	r.list.Position = layout.Position{}
+}
+
+// Len returns the number of matches shown.
+func (r *Results) Len() int {
+	return r.matches
+}
+
+// Adder returns a function adding a match to the pane from any goroutine. It
+// returns false once the pane is reset or full, to stop the search.
+func (r *Results) Adder() func(search.Match) bool {
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	added := 0
+	return func(m search.Match) bool {
+		r.mu.Lock()
+		if r.generation != generation || added >= MaxMatches {
+			r.mu.Unlock()
+			return false
+		}
+		r.queue = append(r.queue, m)
+		added++
+		r.mu.Unlock()
+
+		if r.Invalidate != nil {
+			r.Invalidate()
+		}
+		return true
+	}
+}
+
+// flush takes in the queued matches.
+func (r *Results) flush() {
+	r.mu.Lock()
+	queue := r.queue
+	r.queue = nil
+	r.mu.Unlock()
+
+	for _, m := range queue {
+		f := r.byPath[m.Path]
+		if f == nil {
+			f = &file{path: m.Path}
+			r.byPath[m.Path] = f
+			r.files = append(r.files, f)
+		}
+		f.matches = append(f.matches, m)
+		f.clicks = append(f.clicks, widget.Clickable{})
+		r.matches++
+	}
+}
+
+// Layout draws the pane.
+func (r *Results) Layout(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	r.flush()
+
+	// each file is a header followed by its matches
+	type line struct {
+		file  *file
+		match int
+	}
+	var lines []line
+	for _, f := range r.files {
+		lines = append(lines, line{file: f, match: -1})
+		for i := range f.matches {
+			lines = append(lines, line{file: f, match: i})
+		}
+	}
+
+	for _, f := range r.files {
+		for i := range f.clicks {
+			if f.clicks[i].Clicked(gtx) && r.OnOpen != nil {
+				r.OnOpen(f.matches[i])
+			}
+		}
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return r.layoutTitle(gtx, theme)
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return material.List(theme, &r.list).Layout(gtx, len(lines), func(gtx layout.Context, index int) layout.Dimensions {
+				l := lines[index]
+				if l.match < 0 {
+					return r.layoutFile(gtx, theme, l.file)
+				}
+				return material.Clickable(gtx, &l.file.clicks[l.match], func(gtx layout.Context) layout.Dimensions {
+					return layoutMatch(gtx, theme, l.file.matches[l.match])
+				})
+			})
+		}),
+	)
+}
+
+// layoutTitle draws the number of matches and the close button.
+func (r *Results) layoutTitle(gtx layout.Context, theme *material.Theme) layout.Dimensions {
+	title := fmt.Sprintf("%d matches in %d files", r.matches, len(r.files))
+	if r.matches >= MaxMatches {
+		title = fmt.Sprintf("First %d matches in %d files", r.matches, len(r.files))
+	}
+
+	return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				label := material.Body1(theme, title)
+				label.Font.Weight = font.SemiBold
+				return label.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return material.Clickable(gtx, &r.Close, func(gtx layout.Context) layout.Dimensions {
+					return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Body2(theme, "Close").Layout)
+				})
+			}),
+		)
+	})
+}
+
+// layoutFile draws the header of the matches of f.
+func (r *Results) layoutFile(gtx layout.Context, theme *material.Theme, f *file) layout.Dimensions {
+	name, err := filepath.Rel(r.Root, f.path)
+	if err != nil {
+		name = f.path
+	}
+
+	return layout.Inset{Top: unit.Dp(6), Left: unit.Dp(4), Bottom: unit.Dp(2)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		label := material.Body2(theme, fmt.Sprintf("%s (%d)", name, len(f.matches)))
+		label.Font.Weight = font.Bold
+		label.MaxLines = 1
+		return label.Layout(gtx)
+	})
+}
+
+// layoutMatch draws a matching line with the matches highlighted.
+func layoutMatch(gtx layout.Context, theme *material.Theme, m search.Match) layout.Dimensions {
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(unit.Dp(56))
+			label := material.Body2(theme, fmt.Sprintf("%d", m.Line))
+			label.Color.A = 0x90
+			label.Font.Typeface = "monospace"
+			return label.Layout(gtx)
+		}),
+	}
+
+	for _, s := range segments(m) {
+		s := s
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			label := material.Body2(theme, s.text)
+			label.MaxLines = 1
+			label.Font.Typeface = "monospace"
+			if !s.highlighted {
+				return label.Layout(gtx)
+			}
+
+			label.Font.Weight = font.Bold
+			return layout.Background{}.Layout(gtx,
+				func(gtx layout.Context) layout.Dimensions {
+					highlight := theme.Palette.ContrastBg
+					highlight.A = 0x50
+					defer clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(unit.Dp(2))).Push(gtx.Ops).Pop()
+					paint.Fill(gtx.Ops, highlight)
+					return layout.Dimensions{Size: gtx.Constraints.Min}
+				},
+				label.Layout,
+			)
+		}))
+	}
+
+	return layout.Inset{Left: unit.Dp(12), Top: unit.Dp(1), Bottom: unit.Dp(1)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+	})
+}
+
+// segment is a piece of a matching line.
+type segment struct {
+	text        string
+	highlighted bool
+}
+
+// segments splits the line of m in the parts that matched and the parts
+// around them. The indentation is dropped and long lines are cut.
+func segments(m search.Match) []segment {
+	text := m.Text
+	start := len(text) - len(strings.TrimLeft(text, " \t"))
+	end := len(text)
+	// keep the first match in view on long lines
+	if len(m.Ranges) > 0 && m.Ranges[0][0]-start > maxShownLength/2 {
+		start = m.Ranges[0][0] - maxShownLength/4
+	}
+	if end-start > maxShownLength {
+		end = start + maxShownLength
+	}
+
+	var parts []segment
+	add := func(from, to int, highlighted bool) {
+		from, to = max(from, start), min(to, end)
+		if from < to {
+			parts = append(parts, segment{text: strings.ToValidUTF8(text[from:to], ""), highlighted: highlighted})
+		}
+	}
+
+	last := start
+	for _, r := range m.Ranges {
+		add(last, r[0], false)
+		add(r[0], r[1], true)
+		last = max(last, r[1])
+	}
+	add(last, end, false)
+
+	return parts
+}